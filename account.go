@@ -0,0 +1,25 @@
+package documentdb
+
+import (
+	"context"
+	"strconv"
+)
+
+// Read the database account, i.e: its regions, consistency and media quota
+func (c *DocumentDB) ReadDatabaseAccount(opts ...CallOption) (account *DatabaseAccount, err error) {
+	account, _, err = c.ReadDatabaseAccountWithContext(context.Background(), opts...)
+	return
+}
+
+// ReadDatabaseAccountWithContext reads the database account, the request is bound to ctx
+func (c *DocumentDB) ReadDatabaseAccountWithContext(ctx context.Context, opts ...CallOption) (account *DatabaseAccount, res *Response, err error) {
+	res, err = c.client.ReadWithContext(ctx, "", &account, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	if account != nil && res != nil {
+		account.MaxMediaStorageUsageMB, _ = strconv.ParseInt(res.Header.Get(HeaderMaxMediaStorage), 10, 64)
+		account.MediaStorageUsageMB, _ = strconv.ParseInt(res.Header.Get(HeaderMediaStorage), 10, 64)
+	}
+	return
+}
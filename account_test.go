@@ -0,0 +1,55 @@
+package documentdb
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReadDatabaseAccount(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadDatabaseAccount()
+	client.AssertCalled(t, "Read", "", mock.Anything, mock.Anything)
+}
+
+func TestReadDatabaseAccountModel(t *testing.T) {
+	assert := assert.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/", r.URL.Path)
+		w.Header().Set(HeaderMaxMediaStorage, "10240")
+		w.Header().Set(HeaderMediaStorage, "2")
+		fmt.Fprintln(w, `{
+			"id": "account",
+			"_rid": "account.documents.azure.com",
+			"_dbs": "//dbs/",
+			"media": "//media/",
+			"writableLocations": [{"name": "West US", "databaseAccountEndpoint": "https://account-westus.documents.azure.com:443/"}],
+			"readableLocations": [{"name": "West US", "databaseAccountEndpoint": "https://account-westus.documents.azure.com:443/"}, {"name": "East US", "databaseAccountEndpoint": "https://account-eastus.documents.azure.com:443/"}],
+			"enableMultipleWriteLocations": true,
+			"userConsistencyPolicy": {"defaultConsistencyLevel": "Bounded", "maxStalenessPrefix": 100, "maxStalenessIntervalInSeconds": 5},
+			"userReplicationPolicy": {"asyncReplication": false, "minReplicaSetSize": 3, "maxReplicasetSize": 4},
+			"readPolicy": {"primaryReadCoefficient": 1, "secondaryReadCoefficient": 1}
+		}`)
+	}))
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+	c := &DocumentDB{client, client.Config}
+
+	account, err := c.ReadDatabaseAccount()
+	assert.Nil(err)
+	assert.Equal("account", account.Id)
+	assert.True(account.EnableMultipleWriteLocations)
+	assert.Equal(Bounded, account.ConsistencyPolicy.DefaultConsistencyLevel)
+	assert.Equal(100, account.ConsistencyPolicy.MaxStalenessPrefix)
+	assert.Equal(4, account.ReplicationPolicy.MaxReplicaSetSize)
+	assert.Len(account.ReadableLocations, 2)
+	assert.Equal("East US", account.ReadableLocations[1].Name)
+	assert.Equal(int64(10240), account.MaxMediaStorageUsageMB)
+	assert.Equal(int64(2), account.MediaStorageUsageMB)
+}
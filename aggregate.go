@@ -0,0 +1,421 @@
+package documentdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// typeOrder is the rank of the json types in ORDER BY, MIN and MAX:
+// undefined < null < booleans < numbers < strings < arrays < objects
+func typeOrder(v interface{}, defined bool) int {
+	if !defined {
+		return 0
+	}
+	switch v.(type) {
+	case nil:
+		return 1
+	case bool:
+		return 2
+	case float64, json.Number:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// compareValues compares two json values decoded into interface{}, the
+// values of different types are ordered by typeOrder
+func compareValues(a interface{}, aDefined bool, b interface{}, bDefined bool) int {
+	ta, tb := typeOrder(a, aDefined), typeOrder(b, bDefined)
+	if ta != tb {
+		return ta - tb
+	}
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case y:
+			return -1
+		}
+		return 1
+	case float64, json.Number:
+		fx, fy := toFloat(x), toFloat(b)
+		switch {
+		case fx < fy:
+			return -1
+		case fx > fy:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	}
+	return 0
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	}
+	return 0
+}
+
+// aggregator combines the partial aggregates returned by each partition
+type aggregator interface {
+	add(partial interface{}, defined bool)
+	// result returns the aggregate, ok is false if it's undefined
+	result() (v interface{}, ok bool)
+}
+
+func newAggregator(kind string) (aggregator, error) {
+	switch kind {
+	case "Count":
+		return &countAggregator{}, nil
+	case "Sum":
+		return &sumAggregator{}, nil
+	case "Average":
+		return &avgAggregator{}, nil
+	case "Min":
+		return &minMaxAggregator{key: "min", sign: 1}, nil
+	case "Max":
+		return &minMaxAggregator{key: "max", sign: -1}, nil
+	}
+	return nil, fmt.Errorf("documentdb: unsupported aggregate %q", kind)
+}
+
+type countAggregator struct {
+	count float64
+}
+
+func (a *countAggregator) add(partial interface{}, defined bool) {
+	a.count += toFloat(partial)
+}
+
+func (a *countAggregator) result() (interface{}, bool) {
+	return a.count, true
+}
+
+// sumAggregator is undefined if one of the partial sums is undefined
+type sumAggregator struct {
+	sum       float64
+	undefined bool
+}
+
+func (a *sumAggregator) add(partial interface{}, defined bool) {
+	if typeOrder(partial, defined) != 3 {
+		a.undefined = true
+		return
+	}
+	a.sum += toFloat(partial)
+}
+
+func (a *sumAggregator) result() (interface{}, bool) {
+	return a.sum, !a.undefined
+}
+
+// avgAggregator combines the partial {"sum": s, "count": n} of each partition
+type avgAggregator struct {
+	sum, count float64
+	undefined  bool
+}
+
+func (a *avgAggregator) add(partial interface{}, defined bool) {
+	m, ok := partial.(map[string]interface{})
+	if !defined || !ok {
+		a.undefined = true
+		return
+	}
+	a.sum += toFloat(m["sum"])
+	a.count += toFloat(m["count"])
+}
+
+func (a *avgAggregator) result() (interface{}, bool) {
+	if a.undefined || a.count == 0 {
+		return nil, false
+	}
+	return a.sum / a.count, true
+}
+
+// minMaxAggregator keeps the smallest (sign 1) or greatest (sign -1) partial,
+// partials are either values or {"min"|"max": v, "count": n} objects
+type minMaxAggregator struct {
+	key     string
+	sign    int
+	value   interface{}
+	defined bool
+}
+
+func (a *minMaxAggregator) add(partial interface{}, defined bool) {
+	if m, ok := partial.(map[string]interface{}); ok {
+		if _, ok := m["count"]; ok {
+			if toFloat(m["count"]) == 0 {
+				return
+			}
+			partial, defined = m[a.key]
+		}
+	}
+	if !defined {
+		return
+	}
+	if !a.defined || a.sign*compareValues(partial, true, a.value, true) < 0 {
+		a.value, a.defined = partial, true
+	}
+}
+
+func (a *minMaxAggregator) result() (interface{}, bool) {
+	return a.value, a.defined
+}
+
+// sliceStream returns the results of a slice
+type sliceStream struct {
+	items []json.RawMessage
+}
+
+func (s *sliceStream) next() (json.RawMessage, bool, error) {
+	if len(s.items) == 0 {
+		return nil, false, nil
+	}
+	item := s.items[0]
+	s.items = s.items[1:]
+	return item, true, nil
+}
+
+// item returns the "item" of a partial aggregate
+func item(v interface{}) (interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	v, ok = m["item"]
+	return v, ok
+}
+
+// aggregate combines the results of a `SELECT VALUE <aggregate>` query, each
+// partition returns [{"item": partial}]
+func aggregate(source resultStream, kinds []string) (resultStream, error) {
+	aggs := make([]aggregator, len(kinds))
+	for i, kind := range kinds {
+		agg, err := newAggregator(kind)
+		if err != nil {
+			return nil, err
+		}
+		aggs[i] = agg
+	}
+	for {
+		raw, ok, err := source.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		var partials []interface{}
+		if err := Serialization.Unmarshal(raw, &partials); err != nil {
+			return nil, err
+		}
+		for i, agg := range aggs {
+			if i < len(partials) {
+				agg.add(item(partials[i]))
+			} else {
+				agg.add(nil, false)
+			}
+		}
+	}
+	var values []interface{}
+	for _, agg := range aggs {
+		if v, ok := agg.result(); ok {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return &sliceStream{}, nil
+	}
+	var result interface{} = values
+	if len(aggs) == 1 {
+		result = values[0]
+	}
+	b, err := Serialization.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &sliceStream{items: []json.RawMessage{b}}, nil
+}
+
+// groupByResult is a result of a rewritten GROUP BY query
+type groupByResult struct {
+	GroupByItems json.RawMessage `json:"groupByItems"`
+	Payload      interface{}     `json:"payload"`
+}
+
+// group accumulates the payloads of one group
+type group struct {
+	// aggs holds the aggregates of the projection by alias
+	aggs map[string]aggregator
+	// values holds the group keys of the projection by alias, or the whole
+	// payload of a `SELECT VALUE` query
+	values map[string]interface{}
+	value  interface{}
+}
+
+// groupBy combines the results of a GROUP BY query, or of a query with aggregates
+// in its projection, each partition returns {"groupByItems": [...], "payload": ...}
+func groupBy(source resultStream, info queryInfo) (resultStream, error) {
+	var (
+		groups = make(map[string]*group)
+		keys   []string
+	)
+	for {
+		raw, ok, err := source.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		var result groupByResult
+		if err := Serialization.Unmarshal(raw, &result); err != nil {
+			return nil, err
+		}
+		key, err := canonical(result.GroupByItems)
+		if err != nil {
+			return nil, err
+		}
+		g, ok := groups[key]
+		if !ok {
+			if g, err = newGroup(info); err != nil {
+				return nil, err
+			}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.add(result.Payload, info)
+	}
+	items := make([]json.RawMessage, 0, len(keys))
+	for _, key := range keys {
+		b, ok, err := groups[key].result(info)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			items = append(items, b)
+		}
+	}
+	return &sliceStream{items: items}, nil
+}
+
+func newGroup(info queryInfo) (*group, error) {
+	g := &group{aggs: make(map[string]aggregator), values: make(map[string]interface{})}
+	if info.HasSelectValue && len(info.Aggregates) > 0 {
+		agg, err := newAggregator(info.Aggregates[0])
+		if err != nil {
+			return nil, err
+		}
+		g.aggs[""] = agg
+	}
+	for alias, kind := range info.GroupByAliasToAggregateType {
+		if kind == "" {
+			continue
+		}
+		agg, err := newAggregator(kind)
+		if err != nil {
+			return nil, err
+		}
+		g.aggs[alias] = agg
+	}
+	return g, nil
+}
+
+func (g *group) add(payload interface{}, info queryInfo) {
+	if info.HasSelectValue {
+		if agg, ok := g.aggs[""]; ok {
+			agg.add(item(payload))
+		} else if g.value == nil {
+			g.value = payload
+		}
+		return
+	}
+	m, _ := payload.(map[string]interface{})
+	for alias, v := range m {
+		if agg, ok := g.aggs[alias]; ok {
+			agg.add(item(v))
+		} else if _, ok := g.values[alias]; !ok {
+			g.values[alias] = v
+		}
+	}
+	// Aggregates missing from the payload are undefined
+	for alias, agg := range g.aggs {
+		if _, ok := m[alias]; !ok {
+			agg.add(nil, false)
+		}
+	}
+}
+
+// result returns the json of the group, ok is false if it's undefined
+func (g *group) result(info queryInfo) (json.RawMessage, bool, error) {
+	if info.HasSelectValue {
+		v, ok := g.value, true
+		if agg, hasAgg := g.aggs[""]; hasAgg {
+			v, ok = agg.result()
+		}
+		if !ok {
+			return nil, false, nil
+		}
+		b, err := Serialization.Marshal(v)
+		return b, true, err
+	}
+	aliases := info.GroupByAliases
+	if len(aliases) == 0 {
+		for alias := range info.GroupByAliasToAggregateType {
+			aliases = append(aliases, alias)
+		}
+		for alias := range g.values {
+			if _, ok := info.GroupByAliasToAggregateType[alias]; !ok {
+				aliases = append(aliases, alias)
+			}
+		}
+		sort.Strings(aliases)
+	}
+	// The object is written by hand to keep the order of the projection
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, alias := range aliases {
+		var (
+			v  interface{}
+			ok bool
+		)
+		if agg, isAgg := g.aggs[alias]; isAgg {
+			v, ok = agg.result()
+		} else {
+			v, ok = g.values[alias]
+		}
+		if !ok {
+			continue
+		}
+		b, err := Serialization.Marshal(v)
+		if err != nil {
+			return nil, false, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(alias)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), true, nil
+}
@@ -0,0 +1,137 @@
+package documentdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// ErrStreamingNotSupported is returned by ReadMedia when the client doesn't implement Streamer
+var ErrStreamingNotSupported = errors.New("documentdb: the client doesn't support streaming")
+
+// Read attachment by self link
+func (c *DocumentDB) ReadAttachment(link string, opts ...CallOption) (attachment *Attachment, err error) {
+	attachment, _, err = c.ReadAttachmentWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadAttachmentWithContext reads attachment by self link, the request is bound to ctx
+func (c *DocumentDB) ReadAttachmentWithContext(ctx context.Context, link string, opts ...CallOption) (attachment *Attachment, res *Response, err error) {
+	res, err = c.client.ReadWithContext(ctx, link, &attachment, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Read all attachments by document self link
+func (c *DocumentDB) ReadAttachments(doc string, opts ...CallOption) (attachments []Attachment, err error) {
+	attachments, _, err = c.ReadAttachmentsWithContext(context.Background(), doc, opts...)
+	return
+}
+
+// ReadAttachmentsWithContext reads all attachments by document self link, the request is bound to ctx
+func (c *DocumentDB) ReadAttachmentsWithContext(ctx context.Context, doc string, opts ...CallOption) (attachments []Attachment, res *Response, err error) {
+	return c.QueryAttachmentsWithContext(ctx, doc, nil, opts...)
+}
+
+// Read all document attachments that satisfy a query
+func (c *DocumentDB) QueryAttachments(doc string, query *Query, opts ...CallOption) (attachments []Attachment, err error) {
+	attachments, _, err = c.QueryAttachmentsWithContext(context.Background(), doc, query, opts...)
+	return
+}
+
+// QueryAttachmentsWithContext reads all document attachments that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryAttachmentsWithContext(ctx context.Context, doc string, query *Query, opts ...CallOption) (attachments []Attachment, res *Response, err error) {
+	data := struct {
+		Attachments []Attachment `json:"Attachments,omitempty"`
+		Count       int          `json:"_count,omitempty"`
+	}{}
+	if query != nil {
+		res, err = c.client.QueryWithContext(ctx, doc+"attachments/", query, &data, opts...)
+	} else {
+		res, err = c.client.ReadWithContext(ctx, doc+"attachments/", &data, opts...)
+	}
+	if attachments = data.Attachments; err != nil {
+		attachments = nil
+	}
+	return
+}
+
+// Create attachment, the body references external media
+func (c *DocumentDB) CreateAttachment(doc string, body interface{}, opts ...CallOption) (attachment *Attachment, err error) {
+	attachment, _, err = c.CreateAttachmentWithContext(context.Background(), doc, body, opts...)
+	return
+}
+
+// CreateAttachmentWithContext creates attachment, the request is bound to ctx
+func (c *DocumentDB) CreateAttachmentWithContext(ctx context.Context, doc string, body interface{}, opts ...CallOption) (attachment *Attachment, res *Response, err error) {
+	res, err = c.client.CreateWithContext(ctx, doc+"attachments/", body, &attachment, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Create attachment by uploading its media, slug is the attachment id
+func (c *DocumentDB) CreateAttachmentMedia(doc, slug, contentType string, media io.Reader, opts ...CallOption) (attachment *Attachment, err error) {
+	attachment, _, err = c.CreateAttachmentMediaWithContext(context.Background(), doc, slug, contentType, media, opts...)
+	return
+}
+
+// CreateAttachmentMediaWithContext creates attachment by uploading its media, the request is bound to ctx.
+// The media is read in memory, so the request can be sent again on retries
+func (c *DocumentDB) CreateAttachmentMediaWithContext(ctx context.Context, doc, slug, contentType string, media io.Reader, opts ...CallOption) (attachment *Attachment, res *Response, err error) {
+	body, err := ioutil.ReadAll(media)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = append(opts, mediaHeaders(slug, contentType))
+	res, err = c.client.CreateWithContext(ctx, doc+"attachments/", body, &attachment, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Read media by its link (i.e: Attachment.Media), the caller must close the returned body
+func (c *DocumentDB) ReadMedia(link string, opts ...CallOption) (io.ReadCloser, error) {
+	body, _, err := c.ReadMediaWithContext(context.Background(), link, opts...)
+	return body, err
+}
+
+// ReadMediaWithContext streams media by its link, the request is bound to ctx.
+// The caller must close the returned body. The client must implement Streamer
+func (c *DocumentDB) ReadMediaWithContext(ctx context.Context, link string, opts ...CallOption) (io.ReadCloser, *Response, error) {
+	s, ok := c.client.(Streamer)
+	if !ok {
+		return nil, nil, ErrStreamingNotSupported
+	}
+	return s.StreamWithContext(ctx, link, opts...)
+}
+
+// Replace attachment
+func (c *DocumentDB) ReplaceAttachment(link string, body interface{}, opts ...CallOption) (attachment *Attachment, err error) {
+	attachment, _, err = c.ReplaceAttachmentWithContext(context.Background(), link, body, opts...)
+	return
+}
+
+// ReplaceAttachmentWithContext replaces attachment, the request is bound to ctx
+func (c *DocumentDB) ReplaceAttachmentWithContext(ctx context.Context, link string, body interface{}, opts ...CallOption) (attachment *Attachment, res *Response, err error) {
+	res, err = c.client.ReplaceWithContext(ctx, link, body, &attachment, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Delete attachment
+func (c *DocumentDB) DeleteAttachment(link string, opts ...CallOption) (*Response, error) {
+	return c.DeleteAttachmentWithContext(context.Background(), link, opts...)
+}
+
+// DeleteAttachmentWithContext deletes attachment, the request is bound to ctx
+func (c *DocumentDB) DeleteAttachmentWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.client.DeleteWithContext(ctx, link, opts...)
+}
@@ -0,0 +1,120 @@
+package documentdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReadAttachment(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "attachment_link", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadAttachment("attachment_link")
+	client.AssertCalled(t, "Read", "attachment_link", mock.Anything, mock.Anything)
+}
+
+func TestReadAttachments(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "doc_link/attachments/", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadAttachments("doc_link/")
+	client.AssertCalled(t, "Read", "doc_link/attachments/", mock.Anything, mock.Anything)
+}
+
+func TestQueryAttachments(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	q := NewQuery("SELECT * FROM ROOT r")
+	client.On("Query", "doc_link/attachments/", q).Return(nil)
+	c.QueryAttachments("doc_link/", q)
+	client.AssertCalled(t, "Query", "doc_link/attachments/", q)
+}
+
+func TestCreateAttachment(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	body := `{"id":"image","contentType":"image/png","media":"https://example.com/image.png"}`
+	client.On("Create", "doc_link/attachments/", body).Return(nil)
+	c.CreateAttachment("doc_link/", body)
+	client.AssertCalled(t, "Create", "doc_link/attachments/", body)
+}
+
+func TestCreateAttachmentMedia(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Create", "doc_link/attachments/", []byte("media")).Return(nil)
+	c.CreateAttachmentMedia("doc_link/", "image", "image/png", strings.NewReader("media"))
+	client.AssertCalled(t, "Create", "doc_link/attachments/", []byte("media"))
+}
+
+func TestReplaceAttachment(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Replace", "attachment_link", "{}").Return(nil)
+	c.ReplaceAttachment("attachment_link", "{}")
+	client.AssertCalled(t, "Replace", "attachment_link", "{}")
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Delete", "attachment_link").Return(nil, nil)
+	c.DeleteAttachment("attachment_link")
+	client.AssertCalled(t, "Delete", "attachment_link")
+}
+
+func TestReadMedia(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Stream", "/media/media_id").Return(ioutil.NopCloser(strings.NewReader("media")), nil)
+	body, err := c.ReadMedia("/media/media_id")
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(body)
+	assert.Equal(t, "media", string(b))
+
+	// Streaming is optional
+	c = &DocumentDB{struct{ Clienter }{client}, nil}
+	_, err = c.ReadMedia("/media/media_id")
+	assert.Equal(t, ErrStreamingNotSupported, err)
+}
+
+func TestAttachmentMediaRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	var stored []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			assert.Equal("image", r.Header.Get(HeaderSlug))
+			assert.Equal("image/png", r.Header.Get(HeaderContentType))
+			stored, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, `{"id": "image", "contentType": "image/png", "media": "/media/b5NCAJk5AQAEAAAAAAAAAAAAAAAA"}`)
+		case http.MethodGet:
+			w.Header().Set(HeaderRequestCharge, "2")
+			w.Write(stored)
+		}
+	}))
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+	c := &DocumentDB{client, client.Config}
+
+	attachment, err := c.CreateAttachmentMedia("dbs/db/colls/coll/docs/doc/", "image", "image/png", bytes.NewReader([]byte{1, 2, 3}))
+	assert.Nil(err)
+	assert.Equal("/media/b5NCAJk5AQAEAAAAAAAAAAAAAAAA", attachment.Media)
+
+	body, res, err := c.ReadMediaWithContext(context.Background(), attachment.Media)
+	assert.Nil(err)
+	defer body.Close()
+	b, _ := ioutil.ReadAll(body)
+	assert.Equal([]byte{1, 2, 3}, b)
+	assert.Equal(2.0, res.RequestCharge())
+}
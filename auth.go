@@ -13,20 +13,39 @@ type Key struct {
 	err  error
 }
 
+// NewKey creates a key and decodes it once
 func NewKey(key string) *Key {
-	return &Key{Key: key}
+	k := &Key{Key: key}
+	k.decode()
+	return k
 }
 
-func (k *Key) Salt() ([]byte, error) {
+// decode decodes the key once, it's called by the constructors before the key
+// is shared by concurrent requests
+func (k *Key) decode() {
 	if len(k.salt) == 0 && k.err == nil {
-		k.salt, k.err = base64.StdEncoding.DecodeString(k.Key)
-		if k.err != nil {
-			if _, ok := k.err.(base64.CorruptInputError); ok {
-				k.err = errors.New("base64 input is corrupt, check CosmosDB key.")
-			}
+		k.salt, k.err = decodeKey(k.Key)
+	}
+}
+
+// Salt returns the decoded key. Keys passed to NewKey, NewConfig or New are
+// decoded once, the other ones on each call, Salt never writes the key so it
+// can be shared by concurrent requests and copied
+func (k *Key) Salt() ([]byte, error) {
+	if len(k.salt) > 0 || k.err != nil {
+		return k.salt, k.err
+	}
+	return decodeKey(k.Key)
+}
+
+func decodeKey(key string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		if _, ok := err.(base64.CorruptInputError); ok {
+			err = errors.New("base64 input is corrupt, check CosmosDB key.")
 		}
 	}
-	return k.salt, k.err
+	return salt, err
 }
 
 func authorize(str []byte, key *Key) (ret string, err error) {
@@ -0,0 +1,44 @@
+package documentdb
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySalt(t *testing.T) {
+	assert := assert.New(t)
+	for _, key := range []*Key{NewKey("YXJpZWwNCg=="), {Key: "YXJpZWwNCg=="}} {
+		copied := *key
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				salt, err := copied.Salt()
+				assert.Nil(err)
+				assert.Equal("ariel\r\n", string(salt))
+			}()
+		}
+		wg.Wait()
+	}
+
+	_, err := NewKey("not base64").Salt()
+	assert.EqualError(err, "base64 input is corrupt, check CosmosDB key.")
+	_, err = (&Key{Key: "not base64"}).Salt()
+	assert.EqualError(err, "base64 input is corrupt, check CosmosDB key.")
+}
+
+func TestKeyDecodedByConstructors(t *testing.T) {
+	assert := assert.New(t)
+	config := NewConfig(&Key{Key: "YXJpZWwNCg=="})
+	assert.Equal("ariel\r\n", string(config.MasterKey.salt))
+
+	config = &Config{MasterKey: &Key{Key: "YXJpZWwNCg=="}}
+	New("https://localhost", config)
+	assert.Equal("ariel\r\n", string(config.MasterKey.salt))
+
+	config = NewConfig(&Key{Key: "not base64"})
+	assert.EqualError(config.MasterKey.err, "base64 input is corrupt, check CosmosDB key.")
+}
@@ -0,0 +1,316 @@
+package documentdb
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// QueryBuilder composes a SQL query, every value is sent as a parameter
+// (@p0, @p1, ...) so it's never interpolated in the query text.
+// Field paths, sources and function names are written as is, they must
+// not come from user input.
+//
+//	q := documentdb.Select("c.id", "c.name").
+//		From("c").
+//		Where(documentdb.Gt("c.age", 30), documentdb.ArrayContains("c.tags", "go")).
+//		OrderBy("c.name").
+//		Query()
+//
+// The zero value selects all the fields.
+type QueryBuilder struct {
+	top      int
+	distinct bool
+	value    bool
+	fields   []string
+	from     string
+	joins    []string
+	where    []Expr
+	groupBy  []string
+	orderBy  []string
+	offset   int
+	limit    int
+	limitSet bool
+}
+
+// Select starts a query that selects the given fields, or all of them ("*") if empty
+func Select(fields ...string) *QueryBuilder {
+	return &QueryBuilder{fields: fields}
+}
+
+// SelectValue starts a query that selects the value of expr, e.g: SelectValue("COUNT(1)")
+func SelectValue(expr string) *QueryBuilder {
+	return &QueryBuilder{fields: []string{expr}, value: true}
+}
+
+// Distinct removes duplicates from the results
+func (b *QueryBuilder) Distinct() *QueryBuilder {
+	b.distinct = true
+	return b
+}
+
+// Top limits the number of results
+func (b *QueryBuilder) Top(n int) *QueryBuilder {
+	b.top = n
+	return b
+}
+
+// From sets the source of the query, e.g: "c" or "c IN c.children"
+func (b *QueryBuilder) From(source string) *QueryBuilder {
+	b.from = source
+	return b
+}
+
+// Join adds an intra-document join, e.g: "t IN c.tags"
+func (b *QueryBuilder) Join(source string) *QueryBuilder {
+	b.joins = append(b.joins, source)
+	return b
+}
+
+// Where adds conditions to the query, all of them must be satisfied
+func (b *QueryBuilder) Where(conds ...Expr) *QueryBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// GroupBy groups the results by the given fields
+func (b *QueryBuilder) GroupBy(fields ...string) *QueryBuilder {
+	b.groupBy = append(b.groupBy, fields...)
+	return b
+}
+
+// OrderBy sorts the results by field in ascending order
+func (b *QueryBuilder) OrderBy(field string) *QueryBuilder {
+	b.orderBy = append(b.orderBy, field+" ASC")
+	return b
+}
+
+// OrderByDesc sorts the results by field in descending order
+func (b *QueryBuilder) OrderByDesc(field string) *QueryBuilder {
+	b.orderBy = append(b.orderBy, field+" DESC")
+	return b
+}
+
+// Offset skips the first n results. The service requires OFFSET with LIMIT,
+// Query fails if Limit is not set too
+func (b *QueryBuilder) Offset(n int) *QueryBuilder {
+	b.offset = n
+	return b
+}
+
+// Limit limits the number of results after Offset
+func (b *QueryBuilder) Limit(n int) *QueryBuilder {
+	b.limit = n
+	b.limitSet = true
+	return b
+}
+
+// Query builds the query text and its parameters
+func (b *QueryBuilder) Query() (*Query, error) {
+	if b.offset != 0 && !b.limitSet {
+		return nil, errors.New("documentdb: query OFFSET requires a LIMIT")
+	}
+	var (
+		p   queryParams
+		sql strings.Builder
+	)
+	sql.WriteString("SELECT ")
+	if b.distinct {
+		sql.WriteString("DISTINCT ")
+	}
+	if b.top > 0 {
+		sql.WriteString("TOP " + strconv.Itoa(b.top) + " ")
+	}
+	if b.value {
+		sql.WriteString("VALUE ")
+	}
+	if len(b.fields) == 0 {
+		sql.WriteString("*")
+	} else {
+		sql.WriteString(strings.Join(b.fields, ", "))
+	}
+	if b.from != "" {
+		sql.WriteString(" FROM " + b.from)
+	}
+	for _, join := range b.joins {
+		sql.WriteString(" JOIN " + join)
+	}
+	if len(b.where) > 0 {
+		sql.WriteString(" WHERE " + p.join(b.where, " AND "))
+	}
+	if len(b.groupBy) > 0 {
+		sql.WriteString(" GROUP BY " + strings.Join(b.groupBy, ", "))
+	}
+	if len(b.orderBy) > 0 {
+		sql.WriteString(" ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+	if b.limitSet {
+		sql.WriteString(" OFFSET " + strconv.Itoa(b.offset) + " LIMIT " + strconv.Itoa(b.limit))
+	}
+	return NewQuery(sql.String(), p.params...), nil
+}
+
+// queryParams collects the parameters of a query while its text is written
+type queryParams struct {
+	params []Parameter
+}
+
+// add binds a value to the next parameter and returns its name
+func (p *queryParams) add(value interface{}) string {
+	name := "@p" + strconv.Itoa(len(p.params))
+	p.params = append(p.params, Parameter{Name: name, Value: value})
+	return name
+}
+
+// operand writes v as is if it's an expression, or binds it to a parameter
+func (p *queryParams) operand(v interface{}) string {
+	if e, ok := v.(Expr); ok {
+		return e.sql(p)
+	}
+	return p.add(v)
+}
+
+// field writes v as a field path if it's a string
+func (p *queryParams) field(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return p.operand(v)
+}
+
+func (p *queryParams) join(exprs []Expr, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = e.sql(p)
+	}
+	return strings.Join(parts, sep)
+}
+
+// Expr is an expression of a query: a condition, a function call or a field
+type Expr interface {
+	sql(p *queryParams) string
+}
+
+type exprFunc func(p *queryParams) string
+
+func (f exprFunc) sql(p *queryParams) string {
+	return f(p)
+}
+
+// Field is a field path used where a value is expected, e.g: Eq("c.total", Field("c.paid"))
+func Field(path string) Expr {
+	return exprFunc(func(*queryParams) string {
+		return path
+	})
+}
+
+func compare(op string, left, right interface{}) Expr {
+	return exprFunc(func(p *queryParams) string {
+		return p.field(left) + " " + op + " " + p.operand(right)
+	})
+}
+
+// Comparisons, left is a field path or an Expr, right is a value or an Expr
+
+// Eq is the `left = right` condition
+func Eq(left, right interface{}) Expr { return compare("=", left, right) }
+
+// Ne is the `left != right` condition
+func Ne(left, right interface{}) Expr { return compare("!=", left, right) }
+
+// Gt is the `left > right` condition
+func Gt(left, right interface{}) Expr { return compare(">", left, right) }
+
+// Gte is the `left >= right` condition
+func Gte(left, right interface{}) Expr { return compare(">=", left, right) }
+
+// Lt is the `left < right` condition
+func Lt(left, right interface{}) Expr { return compare("<", left, right) }
+
+// Lte is the `left <= right` condition
+func Lte(left, right interface{}) Expr { return compare("<=", left, right) }
+
+// In is the `left IN (values...)` condition
+func In(left interface{}, values ...interface{}) Expr {
+	return exprFunc(func(p *queryParams) string {
+		if len(values) == 0 {
+			return "false"
+		}
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = p.operand(v)
+		}
+		return p.field(left) + " IN (" + strings.Join(parts, ", ") + ")"
+	})
+}
+
+// And is satisfied by all the conditions
+func And(conds ...Expr) Expr {
+	return exprFunc(func(p *queryParams) string {
+		return "(" + p.join(conds, " AND ") + ")"
+	})
+}
+
+// Or is satisfied by any of the conditions
+func Or(conds ...Expr) Expr {
+	return exprFunc(func(p *queryParams) string {
+		return "(" + p.join(conds, " OR ") + ")"
+	})
+}
+
+// Not negates the condition
+func Not(cond Expr) Expr {
+	return exprFunc(func(p *queryParams) string {
+		return "NOT (" + cond.sql(p) + ")"
+	})
+}
+
+// Func calls a system function, arguments are values or Exprs, e.g:
+// Func("STARTSWITH", Field("c.name"), "jo")
+func Func(name string, args ...interface{}) Expr {
+	return exprFunc(func(p *queryParams) string {
+		parts := make([]string, len(args))
+		for i, arg := range args {
+			parts[i] = p.operand(arg)
+		}
+		return name + "(" + strings.Join(parts, ", ") + ")"
+	})
+}
+
+// CallUDF calls a user defined function, arguments are values or Exprs
+func CallUDF(name string, args ...interface{}) Expr {
+	return Func("udf."+name, args...)
+}
+
+// ArrayContains is satisfied if the array field contains value
+func ArrayContains(field string, value interface{}) Expr {
+	return Func("ARRAY_CONTAINS", Field(field), value)
+}
+
+// ArrayContainsPartial is satisfied if the array field contains an object
+// that includes all the properties of value
+func ArrayContainsPartial(field string, value interface{}) Expr {
+	return Func("ARRAY_CONTAINS", Field(field), value, Field("true"))
+}
+
+// IsDefined is satisfied if the field is set
+func IsDefined(field string) Expr {
+	return Func("IS_DEFINED", Field(field))
+}
+
+// StDistance is the distance, in meters, between the geometry field and a
+// GeoJSON value, e.g: Lt(StDistance("c.location", NewPoint(-122.12, 47.66)), 3000)
+func StDistance(field string, geometry interface{}) Expr {
+	return Func("ST_DISTANCE", Field(field), geometry)
+}
+
+// Point is a GeoJSON point
+type Point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// NewPoint creates GeoJSON point, longitude first
+func NewPoint(longitude, latitude float64) Point {
+	return Point{Type: "Point", Coordinates: [2]float64{longitude, latitude}}
+}
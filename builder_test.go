@@ -0,0 +1,83 @@
+package documentdb
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	assert := assert.New(t)
+	q, err := Select("c.id", "c.name").
+		From("c").
+		Join("t IN c.tags").
+		Where(
+			Gt("c.age", 30),
+			Or(Eq("t", "go"), ArrayContains("c.languages", "rust")),
+			Not(IsDefined("c.deletedAt")),
+		).
+		OrderBy("c.name").
+		OrderByDesc("c._ts").
+		Offset(10).
+		Limit(5).
+		Query()
+	assert.Nil(err)
+	assert.Equal("SELECT c.id, c.name FROM c JOIN t IN c.tags WHERE c.age > @p0 AND (t = @p1 OR ARRAY_CONTAINS(c.languages, @p2)) AND NOT (IS_DEFINED(c.deletedAt)) ORDER BY c.name ASC, c._ts DESC OFFSET 10 LIMIT 5", q.Query)
+	assert.Equal([]Parameter{{"@p0", 30}, {"@p1", "go"}, {"@p2", "rust"}}, q.Parameters)
+}
+
+func TestQueryBuilderSelect(t *testing.T) {
+	assert := assert.New(t)
+	q, err := Select().From("c").Query()
+	assert.Nil(err)
+	assert.Equal("SELECT * FROM c", q.Query)
+	assert.Empty(q.Parameters)
+
+	// The zero value sends no OFFSET and LIMIT
+	q, err = (&QueryBuilder{}).From("c").Query()
+	assert.Nil(err)
+	assert.Equal("SELECT * FROM c", q.Query)
+
+	q, _ = Select("c.city", "COUNT(1) AS n").Distinct().Top(10).From("c").GroupBy("c.city").Query()
+	assert.Equal("SELECT DISTINCT TOP 10 c.city, COUNT(1) AS n FROM c GROUP BY c.city", q.Query)
+
+	q, _ = SelectValue("COUNT(1)").From("c").Where(In("c.status", "open", "pending")).Query()
+	assert.Equal("SELECT VALUE COUNT(1) FROM c WHERE c.status IN (@p0, @p1)", q.Query)
+
+	q, _ = Select().From("c").Where(In("c.status")).Limit(3).Query()
+	assert.Equal("SELECT * FROM c WHERE false OFFSET 0 LIMIT 3", q.Query)
+
+	q, err = Select().From("c").Limit(0).Query()
+	assert.Nil(err)
+	assert.Equal("SELECT * FROM c OFFSET 0 LIMIT 0", q.Query)
+
+	// The service requires OFFSET with LIMIT
+	q, err = Select().From("c").Offset(10).Query()
+	assert.EqualError(err, "documentdb: query OFFSET requires a LIMIT")
+	assert.Nil(q)
+}
+
+func TestQueryBuilderFunctions(t *testing.T) {
+	assert := assert.New(t)
+	q, err := Select().From("c").Where(
+		Lte(StDistance("c.location", NewPoint(-122.12, 47.66)), 3000),
+		Eq(CallUDF("tax", Field("c.total"), 0.2), Field("c.tax")),
+		Func("STARTSWITH", Field("c.name"), "jo"),
+		ArrayContainsPartial("c.items", map[string]string{"sku": "a1"}),
+	).Query()
+	assert.Nil(err)
+	assert.Equal("SELECT * FROM c WHERE ST_DISTANCE(c.location, @p0) <= @p1 AND udf.tax(c.total, @p2) = c.tax AND STARTSWITH(c.name, @p3) AND ARRAY_CONTAINS(c.items, @p4, true)", q.Query)
+	assert.Len(q.Parameters, 5)
+
+	b, err := json.Marshal(q.Parameters[0])
+	assert.Nil(err)
+	assert.Equal(`{"name":"@p0","value":{"type":"Point","coordinates":[-122.12,47.66]}}`, string(b))
+}
+
+func TestQueryBuilderInjection(t *testing.T) {
+	q, err := Select().From("c").Where(Eq("c.name", "x' OR 1=1 --")).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM c WHERE c.name = @p0", q.Query)
+	assert.Equal(t, "x' OR 1=1 --", q.Parameters[0].Value)
+}
@@ -0,0 +1,375 @@
+package documentdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BulkOperationType is the type of a bulk operation
+type BulkOperationType string
+
+const (
+	BulkCreate  BulkOperationType = "Create"
+	BulkUpsert  BulkOperationType = "Upsert"
+	BulkReplace BulkOperationType = "Replace"
+	BulkDelete  BulkOperationType = "Delete"
+)
+
+// BulkOperation is an operation on a document run by a BulkExecutor
+type BulkOperation struct {
+	Type BulkOperationType
+	// PartitionKey holds the values of the partition key paths of the document,
+	// Undefined for the missing ones. Empty for collections without partition key
+	PartitionKey []interface{}
+	// ID of the document, required by replace and delete
+	ID string
+	// Link of the document for replace and delete, by default the link of the
+	// collection followed by "docs/" and the id
+	Link string
+	// Document is the body of create, upsert and replace, it's decoded from the response
+	Document interface{}
+}
+
+// BulkResult is the result of a bulk operation
+type BulkResult struct {
+	// Index is the position of the operation in the stream of operations
+	Index         int
+	Operation     BulkOperation
+	StatusCode    int
+	RequestCharge float64
+	// Throttled is the number of attempts that were throttled
+	Throttled int
+	Err       error
+}
+
+// BulkStats are the aggregate statistics of a bulk execution
+type BulkStats struct {
+	Succeeded     int
+	Failed        int
+	Throttled     int
+	RequestCharge float64
+	Duration      time.Duration
+}
+
+// Defaults of the BulkOptions
+var (
+	DefaultBulkMaxConcurrency         = 32
+	DefaultBulkMaxConcurrencyPerRange = 8
+	DefaultBulkMaxThrottleRetries     = 20
+)
+
+// BulkOptions configures a BulkExecutor
+type BulkOptions struct {
+	// MaxConcurrency is the max number of requests in flight
+	MaxConcurrency int
+	// MaxConcurrencyPerRange is the max number of requests in flight to a partition
+	// key range. The concurrency of a range is halved when it's throttled, and
+	// increased back one by one as the requests succeed
+	MaxConcurrencyPerRange int
+	// MaxThrottleRetries is the max number of retries of a throttled operation,
+	// DefaultBulkMaxThrottleRetries if nil. Zero disables the retries
+	MaxThrottleRetries *int
+}
+
+// BulkExecutor runs operations on the documents of a collection. The operations
+// are grouped by partition key range, and the rate of each range adapts to the
+// throttling of the service
+type BulkExecutor struct {
+	db      *DocumentDB
+	coll    string
+	options BulkOptions
+	retries int
+	cache   *PartitionKeyRangeCache
+}
+
+// NewBulkExecutor creates a bulk executor of the documents of coll
+func NewBulkExecutor(db *DocumentDB, coll string, options BulkOptions) *BulkExecutor {
+	if options.MaxConcurrency <= 0 {
+		options.MaxConcurrency = DefaultBulkMaxConcurrency
+	}
+	if options.MaxConcurrencyPerRange <= 0 {
+		options.MaxConcurrencyPerRange = DefaultBulkMaxConcurrencyPerRange
+	}
+	retries := DefaultBulkMaxThrottleRetries
+	if options.MaxThrottleRetries != nil {
+		retries = *options.MaxThrottleRetries
+	}
+	return &BulkExecutor{db: db, coll: coll, options: options, retries: retries, cache: NewPartitionKeyRangeCache(db)}
+}
+
+// ExecuteAll runs the operations and returns their results in the order of ops.
+// The operations that are not run, e.g: when ctx is done, fail with the error
+// returned by the execution
+func (b *BulkExecutor) ExecuteAll(ctx context.Context, ops []BulkOperation) ([]BulkResult, *BulkStats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	in := make(chan BulkOperation)
+	out := make(chan BulkResult, len(ops))
+	go func() {
+		defer close(in)
+		for _, op := range ops {
+			select {
+			case in <- op:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	stats, err := b.Execute(ctx, in, out)
+	// Stop sending the operations, e.g: when the collection can't be read
+	cancel()
+	close(out)
+	results := make([]BulkResult, len(ops))
+	done := make([]bool, len(ops))
+	for r := range out {
+		results[r.Index] = r
+		done[r.Index] = true
+	}
+	for i := range results {
+		if !done[i] {
+			results[i] = BulkResult{Index: i, Operation: ops[i], Err: err}
+			stats.Failed++
+		}
+	}
+	return results, stats, err
+}
+
+// Execute runs the operations read from ops until it's closed, the result of each
+// operation is sent to results, if not nil, as soon as it's done. It returns once
+// all the operations are done, or ctx is done. The errors of the operations are
+// reported by their result
+func (b *BulkExecutor) Execute(ctx context.Context, ops <-chan BulkOperation, results chan<- BulkResult) (*BulkStats, error) {
+	start := time.Now()
+	stats := &BulkStats{}
+	coll, _, err := b.db.ReadCollectionWithContext(ctx, b.coll)
+	if err != nil {
+		return stats, err
+	}
+	m, err := b.cache.RoutingMap(ctx, b.coll)
+	if err != nil {
+		return stats, err
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		ranges = make(map[string]*bulkRange)
+		sem    = make(chan struct{}, b.options.MaxConcurrency)
+	)
+	done := func(r BulkResult) {
+		mu.Lock()
+		if r.Err != nil {
+			stats.Failed++
+		} else {
+			stats.Succeeded++
+		}
+		stats.Throttled += r.Throttled
+		stats.RequestCharge += r.RequestCharge
+		mu.Unlock()
+		if results != nil {
+			results <- r
+		}
+	}
+
+	for index := 0; ; index++ {
+		var (
+			op BulkOperation
+			ok bool
+		)
+		select {
+		case op, ok = <-ops:
+		case <-ctx.Done():
+		}
+		if !ok {
+			break
+		}
+		id, err := b.rangeOf(coll.PartitionKey, m, op)
+		if err != nil {
+			done(BulkResult{Index: index, Operation: op, Err: err})
+			continue
+		}
+		r, ok := ranges[id]
+		if !ok {
+			r = newBulkRange(b.options.MaxConcurrencyPerRange)
+			ranges[id] = r
+			wg.Add(b.options.MaxConcurrencyPerRange)
+			for i := 0; i < b.options.MaxConcurrencyPerRange; i++ {
+				go func() {
+					defer wg.Done()
+					for item := range r.queue {
+						done(b.run(ctx, r, sem, item))
+					}
+				}()
+			}
+		}
+		r.queue <- bulkItem{index: index, op: op}
+	}
+	for _, r := range ranges {
+		close(r.queue)
+	}
+	wg.Wait()
+	stats.Duration = time.Since(start)
+	return stats, ctx.Err()
+}
+
+// rangeOf returns the id of the partition key range of the operation
+func (b *BulkExecutor) rangeOf(def *PartitionKeyDefinition, m *RoutingMap, op BulkOperation) (string, error) {
+	if def == nil || len(def.Paths) == 0 {
+		return m.ranges[0].PartitionKeyRangeID, nil
+	}
+	if len(op.PartitionKey) != len(def.Paths) {
+		return "", fmt.Errorf("documentdb: %d partition key values for %d paths", len(op.PartitionKey), len(def.Paths))
+	}
+	epk, err := EffectivePartitionKey(*def, op.PartitionKey...)
+	if err != nil {
+		return "", err
+	}
+	r, ok := m.RangeByEPK(epk)
+	if !ok {
+		return "", ErrIncompleteRoutingMap
+	}
+	return r.PartitionKeyRangeID, nil
+}
+
+// run sends the operation until it's not throttled, or the retries are exhausted
+func (b *BulkExecutor) run(ctx context.Context, r *bulkRange, sem chan struct{}, item bulkItem) BulkResult {
+	result := BulkResult{Index: item.index, Operation: item.op}
+	for {
+		if err := r.acquire(ctx); err != nil {
+			result.Err = err
+			return result
+		}
+		var res *Response
+		select {
+		case sem <- struct{}{}:
+			res, result.Err = b.send(ctx, item.op)
+			<-sem
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+		}
+
+		var reqErr *RequestError
+		switch {
+		case res != nil:
+			result.StatusCode = res.StatusCode
+			result.RequestCharge += res.RequestCharge()
+		case errors.As(result.Err, &reqErr):
+			result.StatusCode = reqErr.StatusCode
+			result.RequestCharge += reqErr.RequestCharge
+		default:
+			// The request was not sent, e.g: ctx is done
+			result.StatusCode = 0
+		}
+		throttled := reqErr != nil && reqErr.StatusCode == http.StatusTooManyRequests
+		var retryAfter time.Duration
+		if throttled {
+			retryAfter = reqErr.RetryAfter
+		}
+		r.release(throttled, retryAfter)
+		if !throttled {
+			return result
+		}
+		if result.Throttled++; result.Throttled > b.retries {
+			return result
+		}
+	}
+}
+
+// send sends the request of the operation
+func (b *BulkExecutor) send(ctx context.Context, op BulkOperation) (*Response, error) {
+	opts := []CallOption{withoutRetries()}
+	if len(op.PartitionKey) > 0 {
+		opts = append(opts, partitionKeyValues(op.PartitionKey))
+	}
+	link := op.Link
+	if link == "" {
+		link = b.coll + "docs/" + op.ID + "/"
+	}
+	switch op.Type {
+	case BulkCreate:
+		return b.db.CreateDocumentWithContext(ctx, b.coll, op.Document, opts...)
+	case BulkUpsert:
+		return b.db.UpsertDocumentWithContext(ctx, b.coll, op.Document, opts...)
+	case BulkReplace:
+		return b.db.ReplaceDocumentWithContext(ctx, link, op.Document, opts...)
+	case BulkDelete:
+		return b.db.DeleteDocumentWithContext(ctx, link, opts...)
+	}
+	return nil, fmt.Errorf("documentdb: unsupported bulk operation %q", op.Type)
+}
+
+// bulkItem is an operation and its position in the stream
+type bulkItem struct {
+	index int
+	op    BulkOperation
+}
+
+// bulkRange holds the operations of a partition key range and limits their
+// concurrency: it's halved on throttling and increased by one after a number
+// of successes equal to the limit (AIMD)
+type bulkRange struct {
+	queue chan bulkItem
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	limit, max  int
+	inflight    int
+	successes   int
+	pausedUntil time.Time
+}
+
+func newBulkRange(max int) *bulkRange {
+	r := &bulkRange{queue: make(chan bulkItem, max), limit: max, max: max}
+	r.cond = sync.NewCond(&r.mu)
+	return r
+}
+
+// acquire waits until the range is not paused and below its concurrency limit
+func (r *bulkRange) acquire(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if wait := time.Until(r.pausedUntil); wait > 0 {
+			r.mu.Unlock()
+			err := sleep(ctx, wait)
+			r.mu.Lock()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if r.inflight < r.limit {
+			r.inflight++
+			return nil
+		}
+		// A request is in flight, its release wakes up the waiters
+		r.cond.Wait()
+	}
+}
+
+// release ends a request, a throttled one pauses the range for retryAfter
+func (r *bulkRange) release(throttled bool, retryAfter time.Duration) {
+	r.mu.Lock()
+	r.inflight--
+	if throttled {
+		r.successes = 0
+		if r.limit /= 2; r.limit < 1 {
+			r.limit = 1
+		}
+		if until := time.Now().Add(retryAfter); until.After(r.pausedUntil) {
+			r.pausedUntil = until
+		}
+	} else if r.successes++; r.successes >= r.limit && r.limit < r.max {
+		r.successes = 0
+		r.limit++
+	}
+	r.mu.Unlock()
+	r.cond.Broadcast()
+}
@@ -0,0 +1,280 @@
+package documentdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBulk serves a collection partitioned on /pk with 2 ranges, the documents
+// are stored by id. The first requests of the partition keys in throttle are throttled
+type fakeBulk struct {
+	*MockServer
+
+	mu       sync.Mutex
+	docs     map[string]json.RawMessage
+	throttle map[string]int
+	requests int
+	// maxInflight is the max number of requests served at the same time
+	inflight, maxInflight int
+}
+
+func newFakeBulk() *fakeBulk {
+	f := &fakeBulk{docs: make(map[string]json.RawMessage), throttle: make(map[string]int)}
+	f.MockServer = HandlerFactory(f.serve)
+	return f
+}
+
+func (f *fakeBulk) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(HeaderRequestCharge, "2")
+	path := strings.TrimPrefix(r.URL.Path, "/dbs/db/colls/coll/")
+	switch {
+	case path == "":
+		fmt.Fprint(w, `{"id": "coll", "partitionKey": {"paths": ["/pk"], "kind": "Hash"}}`)
+		return
+	case path == "pkranges/":
+		if r.Header.Get(HeaderIfNonMatch) != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(HeaderEtag, "1")
+		serveRanges(w, []PartitionKeyRange{pkrange("0", "", "7F"), pkrange("1", "7F", "FF")})
+		return
+	}
+
+	f.mu.Lock()
+	f.requests++
+	pk := r.Header.Get(HeaderPartitionKey)
+	if f.throttle[pk] > 0 {
+		f.throttle[pk]--
+		f.mu.Unlock()
+		w.Header().Set(HeaderRetryAfter, "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	if f.inflight++; f.inflight > f.maxInflight {
+		f.maxInflight = f.inflight
+	}
+	f.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inflight--
+
+	id := strings.TrimSuffix(strings.TrimPrefix(path, "docs/"), "/")
+	var body json.RawMessage
+	json.NewDecoder(r.Body).Decode(&body)
+	switch {
+	case r.Method == http.MethodPost:
+		var doc Document
+		json.Unmarshal(body, &doc)
+		if _, ok := f.docs[doc.Id]; ok && r.Header.Get(HeaderUpsert) == "" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"code": "Conflict", "message": "exists"}`)
+			return
+		}
+		f.docs[doc.Id] = body
+		w.WriteHeader(http.StatusCreated)
+	case f.docs[id] == nil:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case r.Method == http.MethodPut:
+		f.docs[id] = body
+	case r.Method == http.MethodDelete:
+		delete(f.docs, id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Write(body)
+}
+
+func (f *fakeBulk) executor(options BulkOptions) *BulkExecutor {
+	db := f.DB()
+	db.config.WithRetryPolicy(DefaultRetryPolicy)
+	return NewBulkExecutor(db, "dbs/db/colls/coll/", options)
+}
+
+type bulkDoc struct {
+	Document
+	PK string `json:"pk"`
+}
+
+func TestBulkExecutor(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeBulk()
+	defer f.Close()
+
+	var ops []BulkOperation
+	for i := 0; i < 50; i++ {
+		doc := &bulkDoc{PK: fmt.Sprintf("pk%d", i%5)}
+		doc.Id = fmt.Sprint(i)
+		ops = append(ops, BulkOperation{Type: BulkCreate, PartitionKey: []interface{}{doc.PK}, Document: doc})
+	}
+	ops = append(ops,
+		BulkOperation{Type: BulkCreate, PartitionKey: []interface{}{"pk0"}, Document: &bulkDoc{Document: Document{Resource: Resource{Id: "0"}}, PK: "pk0"}},
+		BulkOperation{Type: BulkDelete, PartitionKey: []interface{}{"pk0"}, ID: "missing"},
+		BulkOperation{Type: BulkCreate, PartitionKey: []interface{}{"a", "b"}, Document: &bulkDoc{}},
+	)
+	results, stats, err := f.executor(BulkOptions{MaxConcurrency: 4, MaxConcurrencyPerRange: 3}).ExecuteAll(context.Background(), ops)
+	assert.Nil(err)
+	assert.Equal(len(ops), len(results))
+	for i, r := range results[:50] {
+		assert.Equal(i, r.Index)
+		assert.Nil(r.Err)
+		assert.Equal(http.StatusCreated, r.StatusCode)
+		assert.Equal(2.0, r.RequestCharge)
+	}
+	assert.True(errors.Is(results[50].Err, ErrConflict))
+	assert.Equal(http.StatusConflict, results[50].StatusCode)
+	assert.True(errors.Is(results[51].Err, ErrNotFound))
+	assert.EqualError(results[52].Err, "documentdb: 2 partition key values for 1 paths")
+	assert.Equal(50, len(f.docs))
+	assert.True(f.maxInflight <= 4, "max inflight %d", f.maxInflight)
+
+	assert.Equal(50, stats.Succeeded)
+	assert.Equal(3, stats.Failed)
+	assert.Equal(0, stats.Throttled)
+	assert.Equal(104.0, stats.RequestCharge)
+	assert.True(stats.Duration > 0)
+}
+
+func TestBulkExecutorOperations(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeBulk()
+	defer f.Close()
+	f.docs["a"] = json.RawMessage(`{"id": "a", "pk": "x"}`)
+	f.docs["b"] = json.RawMessage(`{"id": "b", "pk": "x"}`)
+
+	replaced := &bulkDoc{Document: Document{Resource: Resource{Id: "a"}}, PK: "x"}
+	upserted := &bulkDoc{Document: Document{Resource: Resource{Id: "b"}}, PK: "y"}
+	results, _, err := f.executor(BulkOptions{}).ExecuteAll(context.Background(), []BulkOperation{
+		{Type: BulkReplace, PartitionKey: []interface{}{"x"}, ID: "a", Document: replaced},
+		{Type: BulkUpsert, PartitionKey: []interface{}{"x"}, Document: upserted},
+		{Type: BulkDelete, PartitionKey: []interface{}{"x"}, Link: "dbs/db/colls/coll/docs/b/"},
+		{Type: "Patch", PartitionKey: []interface{}{"x"}, ID: "a"},
+	})
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, results[0].StatusCode)
+	assert.Equal(http.StatusCreated, results[1].StatusCode)
+	assert.EqualError(results[3].Err, `documentdb: unsupported bulk operation "Patch"`)
+	// b exists whatever the order of the upsert and the delete
+	assert.Nil(results[2].Err)
+	assert.Equal(`{"id":"a","pk":"x"}`, strings.Replace(string(f.docs["a"]), " ", "", -1))
+}
+
+func TestBulkExecutorThrottling(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeBulk()
+	defer f.Close()
+	f.throttle[`["hot"]`] = 5
+	f.throttle[`["cold"]`] = 100
+
+	var ops []BulkOperation
+	for i := 0; i < 10; i++ {
+		doc := &bulkDoc{PK: "hot"}
+		doc.Id = fmt.Sprint(i)
+		ops = append(ops, BulkOperation{Type: BulkUpsert, PartitionKey: []interface{}{"hot"}, Document: doc})
+	}
+	ops = append(ops, BulkOperation{Type: BulkUpsert, PartitionKey: []interface{}{"cold"}, Document: &bulkDoc{PK: "cold"}})
+
+	retries := 3
+	results, stats, err := f.executor(BulkOptions{MaxConcurrencyPerRange: 4, MaxThrottleRetries: &retries}).ExecuteAll(context.Background(), ops)
+	assert.Nil(err)
+	for _, r := range results[:10] {
+		assert.Nil(r.Err)
+	}
+	// The client doesn't retry the throttled requests, the executor does
+	cold := results[10]
+	assert.True(errors.Is(cold.Err, ErrThrottled))
+	assert.Equal(http.StatusTooManyRequests, cold.StatusCode)
+	assert.Equal(4, cold.Throttled)
+	assert.Equal(10, stats.Succeeded)
+	assert.Equal(1, stats.Failed)
+	assert.Equal(9, stats.Throttled)
+	assert.Equal(10+9, f.requests)
+
+	// Zero disables the retries
+	retries = 0
+	results, stats, err = f.executor(BulkOptions{MaxThrottleRetries: &retries}).ExecuteAll(context.Background(), ops[10:])
+	assert.Nil(err)
+	assert.True(errors.Is(results[0].Err, ErrThrottled))
+	assert.Equal(1, results[0].Throttled)
+	assert.Equal(10+9+1, f.requests)
+}
+
+func TestBulkExecutorCancel(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeBulk()
+	defer f.Close()
+	f.throttle[`["hot"]`] = 1000
+
+	var ops []BulkOperation
+	for i := 0; i < 10; i++ {
+		doc := &bulkDoc{PK: "hot"}
+		doc.Id = fmt.Sprint(i)
+		ops = append(ops, BulkOperation{Type: BulkUpsert, PartitionKey: []interface{}{"hot"}, Document: doc})
+	}
+	// The operations that are not run fail with the error of the context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results, stats, err := f.executor(BulkOptions{MaxConcurrencyPerRange: 1}).ExecuteAll(ctx, ops)
+	assert.Equal(context.DeadlineExceeded, err)
+	for i, r := range results {
+		assert.Equal(i, r.Index)
+		assert.Equal(ops[i].Document, r.Operation.Document)
+		assert.NotNil(r.Err)
+	}
+	assert.Equal(0, stats.Succeeded)
+	assert.Equal(len(ops), stats.Failed)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, _, err = f.executor(BulkOptions{}).ExecuteAll(cancelled, ops)
+	assert.True(errors.Is(err, context.Canceled))
+	for i, r := range results {
+		assert.Equal(i, r.Index)
+		assert.True(errors.Is(r.Err, context.Canceled))
+	}
+}
+
+func TestBulkRangeLimit(t *testing.T) {
+	assert := assert.New(t)
+	r := newBulkRange(4)
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		assert.Nil(r.acquire(ctx))
+	}
+	r.release(true, 0)
+	assert.Equal(2, r.limit)
+	r.release(true, 0)
+	r.release(true, 0)
+	assert.Equal(1, r.limit, "the limit is at least 1")
+
+	// The limit increases by one after limit successes
+	r.release(false, 0)
+	assert.Equal(2, r.limit)
+	for i := 0; i < 2; i++ {
+		assert.Nil(r.acquire(ctx))
+		r.release(false, 0)
+	}
+	assert.Equal(3, r.limit)
+
+	// The range is paused after a throttled request
+	assert.Nil(r.acquire(ctx))
+	r.release(true, 20*time.Millisecond)
+	start := time.Now()
+	assert.Nil(r.acquire(ctx))
+	assert.True(time.Since(start) >= 20*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(context.Canceled, r.acquire(cancelled))
+}
@@ -0,0 +1,219 @@
+package documentdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ChangeFeedOptions configures a ChangeFeedIterator, the start position only
+// applies to the ranges without a continuation
+type ChangeFeedOptions struct {
+	// StartFromBeginning reads the changes from the creation of the collection
+	StartFromBeginning bool
+	// StartTime, if set, reads the changes made after it
+	StartTime time.Time
+	// MaxItemCount is the max number of documents of a batch
+	MaxItemCount int
+	// Continuation resumes the iterator from the value of Continuation
+	Continuation string
+}
+
+// ChangeFeedIterator reads the change feed of all the partition key ranges of
+// a collection. The changes are read from now, unless the options set another
+// start position. A split range is replaced by its children, that continue from
+// the continuation of their parent
+type ChangeFeedIterator struct {
+	db      *DocumentDB
+	coll    string
+	docs    interface{}
+	options ChangeFeedOptions
+	opts    []CallOption
+
+	started  bool
+	ranges   []*rangeToken
+	pos      int
+	response *Response
+	err      error
+}
+
+// NewChangeFeedIterator creates an iterator of the change feed of coll, each call
+// to Next decodes a batch of changes into docs
+func NewChangeFeedIterator(db *DocumentDB, coll string, docs interface{}, options ChangeFeedOptions, opts ...CallOption) *ChangeFeedIterator {
+	return &ChangeFeedIterator{
+		db:      db,
+		coll:    coll,
+		docs:    docs,
+		options: options,
+		opts:    opts,
+	}
+}
+
+// Response returns *Response object of the last batch
+func (it *ChangeFeedIterator) Response() *Response {
+	return it.response
+}
+
+// Error returns error from last call
+func (it *ChangeFeedIterator) Error() error {
+	return it.err
+}
+
+// Next decodes the next batch of changes into docs, it returns false when none
+// of the ranges has new changes, or on error. Next can be called again later
+// to read the changes made since
+func (it *ChangeFeedIterator) Next() bool {
+	return it.NextWithContext(context.Background())
+}
+
+// NextWithContext is like Next, but the requests are bound to ctx
+func (it *ChangeFeedIterator) NextWithContext(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		if it.err = it.start(ctx); it.err != nil {
+			return false
+		}
+		it.started = true
+	}
+	opts := it.opts[:len(it.opts):len(it.opts)]
+	if it.options.MaxItemCount > 0 {
+		opts = append(opts, Limit(it.options.MaxItemCount))
+	}
+	// The ranges are read in turn, until one has changes or all of them are read once
+	for idle := 0; idle < len(it.ranges); {
+		r := it.ranges[it.pos]
+		continuation, rangeOpts := r.Token, opts
+		if continuation == "" {
+			switch {
+			case !it.options.StartTime.IsZero():
+				rangeOpts = append(rangeOpts, IfModifiedSince(it.options.StartTime.UTC().Format(http.TimeFormat)))
+			case !it.options.StartFromBeginning:
+				continuation = "*"
+			}
+		}
+		items, next, res, err := it.db.readChanges(ctx, it.coll, r.ID, continuation, rangeOpts)
+		if isSplit(err) {
+			if it.err = it.split(ctx, it.pos); it.err != nil {
+				return false
+			}
+			continue
+		}
+		if err != nil {
+			it.err = err
+			return false
+		}
+		r.Token = next
+		it.pos = (it.pos + 1) % len(it.ranges)
+		if len(items) == 0 {
+			idle++
+			continue
+		}
+		it.response = res
+		data := []byte{'['}
+		data = append(data, bytes.Join(toBytes(items), []byte{','})...)
+		data = append(data, ']')
+		if it.err = Serialization.Unmarshal(data, it.docs); it.err != nil {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// Continuation returns the composite continuation of the batches returned by Next
+func (it *ChangeFeedIterator) Continuation() string {
+	if !it.started {
+		return it.options.Continuation
+	}
+	b, _ := Serialization.Marshal(it.ranges)
+	return string(b)
+}
+
+// start reads the ranges from the continuation or from the collection
+func (it *ChangeFeedIterator) start(ctx context.Context) error {
+	if it.options.Continuation != "" {
+		return Serialization.Unmarshal([]byte(it.options.Continuation), &it.ranges)
+	}
+	ranges, err := it.db.targetRanges(ctx, it.coll, nil, &crossPartitionQuery{})
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		it.ranges = append(it.ranges, &rangeToken{ID: r.PartitionKeyRangeID, Min: r.MinInclusive, Max: r.MaxInclusive})
+	}
+	return nil
+}
+
+// Substatus of the 410 responses of a partition key range that was split or merged
+const (
+	subStatusSplit = 1002
+	subStatusMerge = 1007
+)
+
+// isSplit reports whether err is a 410 of a range that was split or merged,
+// the other 410s are returned to the caller
+func isSplit(err error) bool {
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusGone {
+		return false
+	}
+	return reqErr.SubStatus == subStatusSplit || reqErr.SubStatus == subStatusMerge
+}
+
+// split replaces the range at i by its children, they continue from the
+// continuation of their parent
+func (it *ChangeFeedIterator) split(ctx context.Context, i int) error {
+	parent := it.ranges[i]
+	ranges, err := it.db.targetRanges(ctx, it.coll, []queryRange{{Min: parent.Min, Max: parent.Max, IsMinInclusive: true}}, &crossPartitionQuery{})
+	if err != nil {
+		return err
+	}
+	var children []*rangeToken
+	for _, r := range ranges {
+		if r.PartitionKeyRangeID == parent.ID {
+			// The routing is not updated yet
+			return fmt.Errorf("partition key range %s: %w", parent.ID, ErrGone)
+		}
+		children = append(children, &rangeToken{ID: r.PartitionKeyRangeID, Min: r.MinInclusive, Max: r.MaxInclusive, Token: parent.Token})
+	}
+	it.ranges = append(it.ranges[:i], append(children, it.ranges[i+1:]...)...)
+	if it.pos >= len(it.ranges) {
+		it.pos = 0
+	}
+	return nil
+}
+
+// readChanges reads the next batch of changes of a partition key range, from the
+// etag of the previous batch, "*" for the changes made from now, or from the
+// beginning if empty. It returns the etag of the batch, the continuation is
+// returned unchanged with an empty batch if there are no new changes
+func (c *DocumentDB) readChanges(ctx context.Context, coll, rangeID, continuation string, opts []CallOption) ([]json.RawMessage, string, *Response, error) {
+	var data struct {
+		Documents []json.RawMessage `json:"Documents"`
+	}
+	opts = append(opts[:len(opts):len(opts)], ChangeFeed(), ChangeFeedPartitionRangeID(rangeID))
+	if continuation != "" {
+		opts = append(opts, IfNoneMatch(continuation))
+	}
+	res, err := c.client.ReadWithContext(ctx, coll+"docs/", &data, opts...)
+	if err != nil {
+		var reqErr *RequestError
+		if errors.As(err, &reqErr) && reqErr.StatusCode == http.StatusNotModified {
+			if reqErr.Etag != "" {
+				return nil, reqErr.Etag, nil, nil
+			}
+			if continuation == "*" {
+				continuation = ""
+			}
+			return nil, continuation, nil, nil
+		}
+		return nil, continuation, nil, err
+	}
+	return data.Documents, res.Etag(), res, nil
+}
@@ -0,0 +1,130 @@
+package documentdb
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readAll reads the batches of the iterator until no range has new changes
+func readAll(it *ChangeFeedIterator, docs *[]Document) (ids []string) {
+	for it.Next() {
+		for _, doc := range *docs {
+			ids = append(ids, doc.Id)
+		}
+	}
+	return
+}
+
+func TestChangeFeedIteratorFromBeginning(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{
+		"0": changes("a", "b", "c"),
+		"2": changes("d"),
+	})
+	defer f.Close()
+
+	var docs []Document
+	it := NewChangeFeedIterator(f.DB(), feedColl, &docs, ChangeFeedOptions{StartFromBeginning: true})
+	// The ranges are read in turn, a batch at a time
+	assert.Equal([]string{"a", "b", "d", "c"}, readAll(it, &docs))
+	assert.Nil(it.Error())
+	assert.Equal(`[{"id":"0","min":"","max":"55","token":"3"},{"id":"1","min":"55","max":"AA","token":"0"},{"id":"2","min":"AA","max":"FF","token":"1"}]`, it.Continuation())
+
+	// Next reads the changes made since it returned false
+	assert.Empty(readAll(it, &docs))
+	f.addChanges("1", changes("e")...)
+	f.addChanges("2", changes("f")...)
+	assert.Equal([]string{"e", "f"}, readAll(it, &docs))
+
+	// A new iterator resumes from the continuation
+	continuation := it.Continuation()
+	f.addChanges("0", changes("g")...)
+	it = NewChangeFeedIterator(f.DB(), feedColl, &docs, ChangeFeedOptions{Continuation: continuation})
+	assert.Equal(continuation, it.Continuation())
+	assert.Equal([]string{"g"}, readAll(it, &docs))
+}
+
+func TestChangeFeedIteratorFromNow(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{"0": changes("a", "b")})
+	defer f.Close()
+
+	var docs []Document
+	it := NewChangeFeedIterator(f.DB(), feedColl, &docs, ChangeFeedOptions{})
+	assert.Empty(readAll(it, &docs))
+	assert.Nil(it.Error())
+	f.addChanges("0", changes("c")...)
+	assert.Equal([]string{"c"}, readAll(it, &docs))
+}
+
+func TestChangeFeedIteratorFromTime(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newFakeChangeFeed(map[string][]string{
+		"0": changes("a", "b", "c"),
+		"1": changes("d", "e"),
+	})
+	f.times = map[string][]time.Time{
+		"0": {start.Add(-time.Hour), start.Add(time.Hour), start.Add(2 * time.Hour)},
+		"1": {start.Add(-2 * time.Hour), start.Add(-time.Hour)},
+	}
+	defer f.Close()
+
+	var docs []Document
+	it := NewChangeFeedIterator(f.DB(), feedColl, &docs, ChangeFeedOptions{StartTime: start, MaxItemCount: 10})
+	assert.Equal([]string{"b", "c"}, readAll(it, &docs))
+	assert.Nil(it.Error())
+}
+
+func TestChangeFeedIteratorSplit(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{"0": changes("a", "b")})
+	defer f.Close()
+
+	var docs []Document
+	it := NewChangeFeedIterator(f.DB(), feedColl, &docs, ChangeFeedOptions{StartFromBeginning: true})
+	assert.Equal([]string{"a", "b"}, readAll(it, &docs))
+
+	// Range 0 is split into 3 and 4, their changes include the changes of their parent
+	f.mu.Lock()
+	f.gone["0"] = subStatusSplit
+	f.ranges = append(f.ranges[1:],
+		PartitionKeyRange{PartitionKeyRangeID: "3", MinInclusive: "", MaxInclusive: "2A"},
+		PartitionKeyRange{PartitionKeyRangeID: "4", MinInclusive: "2A", MaxInclusive: "55"},
+	)
+	f.changes["3"] = changes("a", "b", "c")
+	f.changes["4"] = changes("x", "y", "d")
+	f.mu.Unlock()
+
+	assert.Equal([]string{"c", "d"}, readAll(it, &docs))
+	assert.Nil(it.Error())
+	assert.Equal(`[{"id":"3","min":"","max":"2A","token":"3"},{"id":"4","min":"2A","max":"55","token":"3"},{"id":"1","min":"55","max":"AA","token":"0"},{"id":"2","min":"AA","max":"FF","token":"0"}]`, it.Continuation())
+}
+
+func TestChangeFeedIteratorGone(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{})
+	f.gone["1"] = subStatusSplit
+	defer f.Close()
+
+	var docs []Document
+	it := NewChangeFeedIterator(f.DB(), feedColl, &docs, ChangeFeedOptions{})
+	assert.False(it.Next())
+	assert.True(errors.Is(it.Error(), ErrGone), "the range is gone but not split")
+
+	// A 410 that is not a split is returned as is
+	f.mu.Lock()
+	delete(f.gone, "1")
+	f.gone["2"] = 1000
+	f.mu.Unlock()
+	it = NewChangeFeedIterator(f.DB(), feedColl, &docs, ChangeFeedOptions{})
+	assert.False(it.Next())
+	var reqErr *RequestError
+	assert.True(errors.As(it.Error(), &reqErr))
+	assert.Equal(http.StatusGone, reqErr.StatusCode)
+	assert.Equal(1000, reqErr.SubStatus)
+}
@@ -0,0 +1,597 @@
+package documentdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default intervals of the change feed processor
+var (
+	DefaultFeedPollDelay           = 5 * time.Second
+	DefaultLeaseAcquireInterval    = 13 * time.Second
+	DefaultLeaseRenewInterval      = 17 * time.Second
+	DefaultLeaseExpirationInterval = 60 * time.Second
+)
+
+// ChangeFeedHandler processes a batch of changes of a partition key range.
+// The continuation of the range is checkpointed when it returns nil, otherwise
+// the batch is delivered again, so a batch can be processed more than once
+type ChangeFeedHandler func(ctx context.Context, rangeID string, docs []json.RawMessage) error
+
+// ChangeFeedProcessorOptions configures a ChangeFeedProcessor
+type ChangeFeedProcessorOptions struct {
+	// HostName identifies the processor in the leases, a random id if empty
+	HostName string
+	// LeaseCollection is the link of the collection storing the leases,
+	// it must be partitioned on /id
+	LeaseCollection string
+	// LeasePrefix distinguishes the leases of the processors sharing a lease collection
+	LeasePrefix string
+	// StartFromBeginning reads the ranges without a lease from their first change,
+	// otherwise from the time their lease is created
+	StartFromBeginning bool
+	// MaxItemCount is the max number of documents of a batch
+	MaxItemCount int
+	// FeedPollDelay is the delay between the reads of a range without new changes
+	FeedPollDelay time.Duration
+	// LeaseAcquireInterval is the interval between the load balancing rounds
+	LeaseAcquireInterval time.Duration
+	// LeaseRenewInterval is the interval between the renewals of an owned lease
+	LeaseRenewInterval time.Duration
+	// LeaseExpirationInterval is the time after which a lease that is not renewed
+	// can be taken by another host
+	LeaseExpirationInterval time.Duration
+	// OnError, if set, is called with the errors of the background work
+	OnError func(err error)
+}
+
+// Lease is the state of a partition key range stored in the lease collection
+type Lease struct {
+	Document
+	// LeaseToken is the id of the partition key range
+	LeaseToken   string `json:"LeaseToken"`
+	MinInclusive string `json:"minInclusive"`
+	MaxExclusive string `json:"maxExclusive"`
+	// ContinuationToken is the etag of the last processed batch
+	ContinuationToken string `json:"ContinuationToken,omitempty"`
+	// StartTime is the time the range is read from until the first checkpoint,
+	// zero reads it from the beginning
+	StartTime time.Time `json:"startTime"`
+	Owner     string    `json:"Owner,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// expired reports whether the lease can be taken by another host
+func (l *Lease) expired(now time.Time, expiration time.Duration) bool {
+	return l.Owner == "" || now.Sub(l.Timestamp) > expiration
+}
+
+// ChangeFeedProcessor reads the change feed of a collection and delivers its
+// batches to a ChangeFeedHandler. The partition key ranges are distributed among
+// the processors sharing the lease collection, each range is read by the host
+// owning its lease
+type ChangeFeedProcessor struct {
+	db      *DocumentDB
+	coll    string
+	handler ChangeFeedHandler
+	options ChangeFeedProcessorOptions
+
+	mu      sync.Mutex
+	workers map[string]*leaseWorker
+	started bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// leaseWorker reads the range of an owned lease, mu guards the lease that is
+// checkpointed by the worker and renewed in the background
+type leaseWorker struct {
+	mu    sync.Mutex
+	lease *Lease
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewChangeFeedProcessor creates a processor of the change feed of coll, it's
+// started by Start
+func NewChangeFeedProcessor(db *DocumentDB, coll string, handler ChangeFeedHandler, options ChangeFeedProcessorOptions) *ChangeFeedProcessor {
+	if options.HostName == "" {
+		options.HostName = uuid()
+	}
+	if options.FeedPollDelay <= 0 {
+		options.FeedPollDelay = DefaultFeedPollDelay
+	}
+	if options.LeaseAcquireInterval <= 0 {
+		options.LeaseAcquireInterval = DefaultLeaseAcquireInterval
+	}
+	if options.LeaseRenewInterval <= 0 {
+		options.LeaseRenewInterval = DefaultLeaseRenewInterval
+	}
+	if options.LeaseExpirationInterval <= 0 {
+		options.LeaseExpirationInterval = DefaultLeaseExpirationInterval
+	}
+	return &ChangeFeedProcessor{
+		db:      db,
+		coll:    coll,
+		handler: handler,
+		options: options,
+		workers: make(map[string]*leaseWorker),
+	}
+}
+
+// HostName returns the name of the processor in the leases
+func (p *ChangeFeedProcessor) HostName() string {
+	return p.options.HostName
+}
+
+// Start creates the missing leases, acquires the first ones and starts the
+// processing in the background. ctx bounds the whole processing, Stop ends
+// it gracefully
+func (p *ChangeFeedProcessor) Start(ctx context.Context) error {
+	if p.options.LeaseCollection == "" {
+		return errors.New("documentdb: change feed processor requires a lease collection")
+	}
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		return errors.New("documentdb: change feed processor already started")
+	}
+	p.started = true
+	stop, done := make(chan struct{}), make(chan struct{})
+	p.stop, p.done = stop, done
+	p.mu.Unlock()
+
+	if err := p.balance(ctx); err != nil {
+		// Release the leases acquired before the failure
+		close(stop)
+		p.wait()
+		p.mu.Lock()
+		p.started = false
+		p.mu.Unlock()
+		return err
+	}
+	go p.run(ctx, stop, done)
+	return nil
+}
+
+// Stop stops the load balancing, waits for the batches being processed to be
+// checkpointed and releases the owned leases, so other hosts take them at once
+func (p *ChangeFeedProcessor) Stop() {
+	p.mu.Lock()
+	if !p.started {
+		p.mu.Unlock()
+		return
+	}
+	p.started = false
+	stop, done := p.stop, p.done
+	p.mu.Unlock()
+
+	close(stop)
+	<-done
+}
+
+// run balances the leases until the processor is stopped
+func (p *ChangeFeedProcessor) run(ctx context.Context, stop, done chan struct{}) {
+	defer close(done)
+	defer p.wait()
+	ticker := time.NewTicker(p.options.LeaseAcquireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.balance(ctx); err != nil {
+				p.report(err)
+			}
+		}
+	}
+}
+
+// wait waits for the workers to exit
+func (p *ChangeFeedProcessor) wait() {
+	p.mu.Lock()
+	workers := make([]*leaseWorker, 0, len(p.workers))
+	for _, w := range p.workers {
+		workers = append(workers, w)
+	}
+	p.mu.Unlock()
+	for _, w := range workers {
+		<-w.done
+	}
+}
+
+func (p *ChangeFeedProcessor) report(err error) {
+	if p.options.OnError != nil && err != nil {
+		p.options.OnError(err)
+	}
+}
+
+// balance creates the leases of the new ranges and acquires the leases this
+// host needs to own an equal share of them
+func (p *ChangeFeedProcessor) balance(ctx context.Context) error {
+	leases, err := p.readLeases(ctx)
+	if err != nil {
+		return err
+	}
+	if leases, err = p.createLeases(ctx, leases); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	owners := map[string]int{p.options.HostName: 0}
+	var available []*Lease
+	for _, l := range leases {
+		if l.expired(now, p.options.LeaseExpirationInterval) {
+			available = append(available, l)
+			continue
+		}
+		owners[l.Owner]++
+		if l.Owner == p.options.HostName {
+			// The lease may be owned by a worker that exited on error
+			p.startWorker(ctx, l, false)
+		}
+	}
+	target := (len(leases) + len(owners) - 1) / len(owners)
+	need := target - owners[p.options.HostName]
+	if need <= 0 {
+		return nil
+	}
+	if len(available) == 0 {
+		// Steal one lease of the host owning the most, if it owns more than its share
+		var (
+			host string
+			max  int
+		)
+		for owner, n := range owners {
+			if n > max || (n == max && owner < host) {
+				host, max = owner, n
+			}
+		}
+		if max <= target {
+			return nil
+		}
+		for _, l := range leases {
+			if l.Owner == host {
+				available = append(available, l)
+				break
+			}
+		}
+		need = 1
+	}
+	for _, l := range available {
+		if need == 0 {
+			break
+		}
+		if err := p.acquire(ctx, l); err != nil {
+			if errors.Is(err, ErrPreconditionFailed) {
+				// Taken by another host
+				continue
+			}
+			return err
+		}
+		need--
+	}
+	return nil
+}
+
+// readLeases reads the leases with the prefix of the processor
+func (p *ChangeFeedProcessor) readLeases(ctx context.Context) ([]*Lease, error) {
+	var (
+		leases       []*Lease
+		continuation string
+	)
+	query := NewQuery("SELECT * FROM c WHERE STARTSWITH(c.id, @prefix)", Parameter{"@prefix", p.options.LeasePrefix})
+	for {
+		var page []*Lease
+		res, err := p.db.QueryDocumentsWithContext(ctx, p.options.LeaseCollection, query, &page, CrossPartition(), Continuation(continuation))
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, page...)
+		if res == nil || res.Continuation() == "" {
+			break
+		}
+		continuation = res.Continuation()
+	}
+	return leases, nil
+}
+
+// createLeases creates the leases of the ranges that don't have one, the children
+// of a range that is split get their lease when the split is detected
+func (p *ChangeFeedProcessor) createLeases(ctx context.Context, leases []*Lease) ([]*Lease, error) {
+	ranges, err := p.db.targetRanges(ctx, p.coll, nil, &crossPartitionQuery{})
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]bool, len(ranges))
+	for _, r := range ranges {
+		current[r.PartitionKeyRangeID] = true
+	}
+	leased := make(map[string]bool, len(leases))
+	var parents []queryRange
+	for _, l := range leases {
+		leased[l.LeaseToken] = true
+		if !current[l.LeaseToken] {
+			parents = append(parents, queryRange{Min: l.MinInclusive, Max: l.MaxExclusive, IsMinInclusive: true})
+		}
+	}
+	for _, r := range ranges {
+		if leased[r.PartitionKeyRangeID] || overlapsAny(parents, r) {
+			continue
+		}
+		var start time.Time
+		if !p.options.StartFromBeginning {
+			start = time.Now().UTC()
+		}
+		l, err := p.createLease(ctx, r, "", start)
+		if err != nil {
+			return nil, err
+		}
+		if l != nil {
+			leases = append(leases, l)
+		}
+	}
+	return leases, nil
+}
+
+func overlapsAny(ranges []queryRange, r PartitionKeyRange) bool {
+	for _, q := range ranges {
+		if q.overlaps(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// createLease creates the lease of a range, it returns nil if it already exists
+func (p *ChangeFeedProcessor) createLease(ctx context.Context, r PartitionKeyRange, continuation string, start time.Time) (*Lease, error) {
+	l := &Lease{
+		LeaseToken:        r.PartitionKeyRangeID,
+		MinInclusive:      r.MinInclusive,
+		MaxExclusive:      r.MaxInclusive,
+		ContinuationToken: continuation,
+		StartTime:         start,
+	}
+	l.Id = p.options.LeasePrefix + r.PartitionKeyRangeID
+	if _, err := p.db.CreateDocumentWithContext(ctx, p.options.LeaseCollection, l, PartitionKey(l.Id)); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+// updateLease upserts the lease if it was not modified since it was read
+func (p *ChangeFeedProcessor) updateLease(ctx context.Context, l *Lease) error {
+	l.Timestamp = time.Now().UTC()
+	_, err := p.db.UpsertDocumentWithContext(ctx, p.options.LeaseCollection, l, PartitionKey(l.Id), IfMatch(l.Etag))
+	return err
+}
+
+// acquire takes the ownership of the lease and starts reading its range
+func (p *ChangeFeedProcessor) acquire(ctx context.Context, l *Lease) error {
+	l.Owner = p.options.HostName
+	if err := p.updateLease(ctx, l); err != nil {
+		return err
+	}
+	p.startWorker(ctx, l, true)
+	return nil
+}
+
+// startWorker starts reading the range of a copy of the lease. If the range is
+// already read, the worker is kept unless replace is set, the new worker then
+// starts after the previous one, that lost the lease, exits
+func (p *ChangeFeedProcessor) startWorker(ctx context.Context, l *Lease, replace bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prev, ok := p.workers[l.Id]
+	if ok && !replace {
+		return
+	}
+	lease := *l
+	w := &leaseWorker{lease: &lease, stop: p.stop, done: make(chan struct{})}
+	p.workers[l.Id] = w
+	go func() {
+		if prev != nil {
+			<-prev.done
+		}
+		p.work(ctx, w)
+	}()
+}
+
+// work reads the changes of the range of the lease and checkpoints them after
+// they're processed, until the processor is stopped or the lease is lost.
+// The lease is renewed in the background while the batches are processed
+func (p *ChangeFeedProcessor) work(ctx context.Context, w *leaseWorker) {
+	// The lease is decoded again by each update, its range is read once
+	l, rangeID := w.lease, w.lease.LeaseToken
+	ctx, cancel := context.WithCancel(ctx)
+	quit, renewed := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(renewed)
+		p.renew(ctx, cancel, quit, w)
+	}()
+	var stopped bool
+	defer func() {
+		// The renewal in flight completes, so the lease is released with its last etag
+		close(quit)
+		<-renewed
+		cancel()
+		if stopped {
+			p.release(w.lease)
+		}
+		p.mu.Lock()
+		if p.workers[w.lease.Id] == w {
+			delete(p.workers, w.lease.Id)
+		}
+		p.mu.Unlock()
+		close(w.done)
+	}()
+	opts := []CallOption{}
+	if p.options.MaxItemCount > 0 {
+		opts = append(opts, Limit(p.options.MaxItemCount))
+	}
+	for {
+		select {
+		case <-w.stop:
+			stopped = true
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		w.mu.Lock()
+		continuation, start := l.ContinuationToken, l.StartTime
+		w.mu.Unlock()
+		rangeOpts := opts
+		if continuation == "" {
+			switch {
+			case !start.IsZero():
+				rangeOpts = append(opts[:len(opts):len(opts)], IfModifiedSince(start.UTC().Format(http.TimeFormat)))
+			case !p.options.StartFromBeginning:
+				// A lease created before the start time was recorded
+				continuation = "*"
+			}
+		}
+		docs, next, _, err := p.db.readChanges(ctx, p.coll, rangeID, continuation, rangeOpts)
+		switch {
+		case ctx.Err() != nil:
+			// The lease was lost, or the processor is done
+			return
+		case isSplit(err):
+			w.mu.Lock()
+			err = p.split(ctx, l)
+			w.mu.Unlock()
+			if err != nil {
+				p.report(err)
+				break
+			}
+			return
+		case err != nil:
+			p.report(err)
+		case len(docs) > 0:
+			if err = p.handler(ctx, rangeID, docs); err != nil {
+				p.report(err)
+				break
+			}
+			if err = p.checkpoint(ctx, w, next); err != nil {
+				// The lease was taken by another host, it resumes from the last checkpoint
+				p.report(err)
+				return
+			}
+			continue
+		case next != continuation:
+			if err = p.checkpoint(ctx, w, next); err != nil {
+				p.report(err)
+				return
+			}
+		}
+
+		select {
+		case <-w.stop:
+			stopped = true
+			return
+		case <-ctx.Done():
+			return
+		case <-time.After(p.options.FeedPollDelay):
+		}
+	}
+}
+
+// checkpoint stores the continuation of the last processed batch in the lease
+func (p *ChangeFeedProcessor) checkpoint(ctx context.Context, w *leaseWorker, continuation string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lease.ContinuationToken = continuation
+	return p.updateLease(ctx, w.lease)
+}
+
+// renew renews the lease every LeaseRenewInterval, whatever the time the batches
+// take to be processed, until quit is closed or ctx is done. The worker is
+// cancelled when the lease was taken by another host
+func (p *ChangeFeedProcessor) renew(ctx context.Context, cancel context.CancelFunc, quit chan struct{}, w *leaseWorker) {
+	ticker := time.NewTicker(p.options.LeaseRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		w.mu.Lock()
+		err := p.updateLease(ctx, w.lease)
+		id := w.lease.Id
+		w.mu.Unlock()
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, ErrPreconditionFailed):
+			p.report(fmt.Errorf("lease %s is lost: %w", id, err))
+			cancel()
+			return
+		case err != nil:
+			p.report(err)
+		}
+	}
+}
+
+// release gives up the ownership of the lease
+func (p *ChangeFeedProcessor) release(l *Lease) {
+	l.Owner = ""
+	if err := p.updateLease(context.Background(), l); err != nil && !errors.Is(err, ErrPreconditionFailed) {
+		p.report(err)
+	}
+}
+
+// split replaces the lease of a range that is split by the leases of its children,
+// they continue from the continuation of their parent, or from its start time
+// when it has no checkpoint
+func (p *ChangeFeedProcessor) split(ctx context.Context, l *Lease) error {
+	parent := queryRange{Min: l.MinInclusive, Max: l.MaxExclusive, IsMinInclusive: true}
+	ranges, err := p.db.targetRanges(ctx, p.coll, []queryRange{parent}, &crossPartitionQuery{})
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		if r.PartitionKeyRangeID == l.LeaseToken {
+			// The routing is not updated yet
+			return fmt.Errorf("partition key range %s: %w", l.LeaseToken, ErrGone)
+		}
+	}
+	for _, r := range ranges {
+		if _, err := p.createLease(ctx, r, l.ContinuationToken, l.StartTime); err != nil {
+			return err
+		}
+	}
+	_, err = p.db.DeleteDocumentWithContext(ctx, l.Self, PartitionKey(l.Id), IfMatch(l.Etag))
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrPreconditionFailed) {
+		return nil
+	}
+	return err
+}
+
+// Leases returns the leases of the processor sorted by range, it's useful to
+// monitor the progress and the distribution of the ranges
+func (p *ChangeFeedProcessor) Leases(ctx context.Context) ([]*Lease, error) {
+	leases, err := p.readLeases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].MinInclusive < leases[j].MinInclusive
+	})
+	return leases, nil
+}
@@ -0,0 +1,530 @@
+package documentdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	feedColl  = "dbs/db/colls/coll/"
+	leaseColl = "dbs/db/colls/leases/"
+)
+
+// fakeChangeFeed serves the change feed of the ranges of a collection, and a
+// lease collection with etags. The etag of a range is the number of changes read
+type fakeChangeFeed struct {
+	*MockServer
+	pageSize int
+
+	mu      sync.Mutex
+	ranges  []PartitionKeyRange
+	changes map[string][]string
+	// times holds the time of the changes, for the If-Modified-Since requests
+	times map[string][]time.Time
+	// gone holds the substatus of the ranges that are gone, 1002 when split
+	gone   map[string]int
+	leases map[string]map[string]interface{}
+	etag   int
+}
+
+func newFakeChangeFeed(changes map[string][]string) *fakeChangeFeed {
+	f := &fakeChangeFeed{
+		pageSize: 2,
+		ranges: []PartitionKeyRange{
+			{PartitionKeyRangeID: "0", MinInclusive: "", MaxInclusive: "55"},
+			{PartitionKeyRangeID: "1", MinInclusive: "55", MaxInclusive: "AA"},
+			{PartitionKeyRangeID: "2", MinInclusive: "AA", MaxInclusive: "FF"},
+		},
+		changes: changes,
+		times:   make(map[string][]time.Time),
+		gone:    make(map[string]int),
+		leases:  make(map[string]map[string]interface{}),
+	}
+	f.MockServer = HandlerFactory(f.serve)
+	return f
+}
+
+func (f *fakeChangeFeed) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/pkranges/"):
+		serveRanges(w, f.ranges)
+	case strings.HasPrefix(r.URL.Path, "/"+feedColl):
+		f.serveChanges(w, r)
+	case r.Header.Get(HeaderIsQuery) == "true":
+		var leases []map[string]interface{}
+		for _, l := range f.leases {
+			leases = append(leases, l)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Documents": leases})
+	case r.Method == http.MethodDelete:
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"+leaseColl+"docs/"), "/")
+		if !f.matches(w, r, id) {
+			return
+		}
+		delete(f.leases, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		var l map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&l)
+		id := l["id"].(string)
+		if r.Header.Get(HeaderUpsert) == "" && f.leases[id] != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if !f.matches(w, r, id) {
+			return
+		}
+		f.etag++
+		l["_etag"] = strconv.Itoa(f.etag)
+		l["_self"] = leaseColl + "docs/" + id + "/"
+		f.leases[id] = l
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(l)
+	}
+}
+
+// matches checks the If-Match header of a lease request
+func (f *fakeChangeFeed) matches(w http.ResponseWriter, r *http.Request, id string) bool {
+	etag := r.Header.Get(HeaderIfMatch)
+	if etag != "" && (f.leases[id] == nil || f.leases[id]["_etag"] != etag) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+func (f *fakeChangeFeed) serveChanges(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(HeaderPartitionKeyRangeID)
+	if r.Header.Get(HeaderAIM) == "" || f.gone[id] != 0 {
+		w.Header().Set(HeaderSubStatus, strconv.Itoa(f.gone[id]))
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	changes := f.changes[id]
+	start, _ := strconv.Atoi(r.Header.Get(HeaderIfNonMatch))
+	if r.Header.Get(HeaderIfNonMatch) == "*" {
+		start = len(changes)
+	}
+	if since, err := http.ParseTime(r.Header.Get(HeaderIfModifiedSince)); err == nil && r.Header.Get(HeaderIfNonMatch) == "" {
+		for start < len(f.times[id]) && !f.times[id][start].After(since) {
+			start++
+		}
+	}
+	if start >= len(changes) {
+		w.Header().Set(HeaderEtag, strconv.Itoa(start))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	end := start + f.pageSize
+	if end > len(changes) {
+		end = len(changes)
+	}
+	w.Header().Set(HeaderEtag, strconv.Itoa(end))
+	fmt.Fprintf(w, `{"Documents": [%s]}`, strings.Join(changes[start:end], ","))
+}
+
+func (f *fakeChangeFeed) addChanges(id string, changes ...string) {
+	f.mu.Lock()
+	f.changes[id] = append(f.changes[id], changes...)
+	f.mu.Unlock()
+}
+
+// owners returns the number of leases of each owner
+func (f *fakeChangeFeed) owners() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	owners := make(map[string]int)
+	for _, l := range f.leases {
+		owner, _ := l["Owner"].(string)
+		owners[owner]++
+	}
+	return owners
+}
+
+func (f *fakeChangeFeed) processor(host string, handler ChangeFeedHandler) *ChangeFeedProcessor {
+	return NewChangeFeedProcessor(f.DB(), feedColl, handler, ChangeFeedProcessorOptions{
+		HostName:                host,
+		LeaseCollection:         leaseColl,
+		LeasePrefix:             "coll.",
+		StartFromBeginning:      true,
+		FeedPollDelay:           5 * time.Millisecond,
+		LeaseAcquireInterval:    10 * time.Millisecond,
+		LeaseRenewInterval:      20 * time.Millisecond,
+		LeaseExpirationInterval: time.Second,
+	})
+}
+
+// eventually waits up to a second for cond to be true
+func eventually(t *testing.T, cond func() bool, msgAndArgs ...interface{}) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return assert.Fail(t, "condition not satisfied", msgAndArgs...)
+}
+
+func changes(ids ...string) (changes []string) {
+	for _, id := range ids {
+		changes = append(changes, fmt.Sprintf(`{"id": %q}`, id))
+	}
+	return
+}
+
+// collector is a handler recording the ids of the changes
+type collector struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (c *collector) handle(ctx context.Context, rangeID string, docs []json.RawMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, doc := range docs {
+		var d Document
+		json.Unmarshal(doc, &d)
+		c.ids = append(c.ids, d.Id)
+	}
+	return nil
+}
+
+func (c *collector) sorted() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := append([]string(nil), c.ids...)
+	sort.Strings(ids)
+	return ids
+}
+
+func TestChangeFeedProcessor(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{
+		"0": changes("a", "b", "c"),
+		"1": changes("d"),
+		"2": changes("e", "f", "g", "h", "i"),
+	})
+	defer f.Close()
+
+	c := &collector{}
+	p := f.processor("host", c.handle)
+	assert.Nil(p.Start(context.Background()))
+	all := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}
+	eventually(t, func() bool { return len(c.sorted()) == len(all) })
+
+	f.addChanges("1", changes("j")...)
+	eventually(t, func() bool { return len(c.sorted()) == len(all)+1 })
+	p.Stop()
+	assert.Equal(append(all, "j"), c.sorted())
+
+	leases, err := p.Leases(context.Background())
+	assert.Nil(err)
+	assert.Equal(3, len(leases))
+	for i, want := range []string{"3", "2", "5"} {
+		assert.Equal(strconv.Itoa(i), leases[i].LeaseToken)
+		assert.Equal("coll."+strconv.Itoa(i), leases[i].Id)
+		assert.Equal(want, leases[i].ContinuationToken)
+		assert.Equal("", leases[i].Owner, "leases are released on stop")
+	}
+
+	// A new processor resumes from the checkpoints
+	c = &collector{}
+	p = f.processor("host", c.handle)
+	assert.Nil(p.Start(context.Background()))
+	f.addChanges("2", changes("k")...)
+	eventually(t, func() bool { return len(c.sorted()) == 1 })
+	p.Stop()
+	assert.Equal([]string{"k"}, c.sorted())
+}
+
+func TestChangeFeedProcessorRedelivery(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{"0": changes("a", "b", "c")})
+	defer f.Close()
+
+	var (
+		mu       sync.Mutex
+		failed   bool
+		received []string
+		errs     []error
+	)
+	handler := func(ctx context.Context, rangeID string, docs []json.RawMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, doc := range docs {
+			var d Document
+			json.Unmarshal(doc, &d)
+			received = append(received, d.Id)
+		}
+		if !failed {
+			failed = true
+			return fmt.Errorf("handler failed")
+		}
+		return nil
+	}
+	p := f.processor("host", handler)
+	p.options.OnError = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	assert.Nil(p.Start(context.Background()))
+	eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 5
+	})
+	p.Stop()
+	assert.Equal([]string{"a", "b", "a", "b", "c"}, received, "the failed batch is delivered again")
+	assert.Equal([]error{fmt.Errorf("handler failed")}, errs)
+}
+
+func TestChangeFeedProcessorStartFromNow(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{"0": changes("a", "b")})
+	defer f.Close()
+
+	f.times = map[string][]time.Time{"0": {time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)}}
+
+	c := &collector{}
+	p := f.processor("host", c.handle)
+	p.options.StartFromBeginning = false
+	assert.Nil(p.Start(context.Background()))
+	eventually(t, func() bool {
+		leases, _ := p.Leases(context.Background())
+		return len(leases) == 3 && leases[0].ContinuationToken == "2"
+	})
+	f.addChanges("0", changes("c")...)
+	eventually(t, func() bool { return len(c.sorted()) == 1 })
+	p.Stop()
+	assert.Equal([]string{"c"}, c.sorted())
+}
+
+func TestChangeFeedProcessorSplitBeforeCheckpoint(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	f := newFakeChangeFeed(map[string][]string{})
+	f.ranges = f.ranges[:1]
+	// The range is gone before its first read
+	f.gone["0"] = subStatusSplit
+	defer f.Close()
+
+	c := &collector{}
+	p := f.processor("host", c.handle)
+	p.options.StartFromBeginning = false
+	assert.Nil(p.Start(context.Background()))
+	defer p.Stop()
+	leases, err := p.Leases(context.Background())
+	assert.Nil(err)
+	if assert.Equal(1, len(leases)) {
+		assert.Equal("", leases[0].ContinuationToken)
+		assert.False(leases[0].StartTime.IsZero())
+	}
+
+	// The children are read from the start time of their parent
+	f.mu.Lock()
+	f.ranges = []PartitionKeyRange{
+		{PartitionKeyRangeID: "1", MinInclusive: "", MaxInclusive: "2A"},
+		{PartitionKeyRangeID: "2", MinInclusive: "2A", MaxInclusive: "55"},
+	}
+	f.changes["1"] = changes("a", "b")
+	f.times["1"] = []time.Time{now.Add(-time.Hour), now.Add(time.Hour)}
+	f.changes["2"] = changes("c")
+	f.times["2"] = []time.Time{now.Add(time.Hour)}
+	f.mu.Unlock()
+	eventually(t, func() bool { return len(c.sorted()) == 2 })
+	assert.Equal([]string{"b", "c"}, c.sorted())
+}
+
+func TestChangeFeedProcessorBalance(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{})
+	f.ranges = append(f.ranges, PartitionKeyRange{PartitionKeyRangeID: "3", MinInclusive: "FF", MaxInclusive: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"})
+	defer f.Close()
+
+	c := &collector{}
+	a := f.processor("a", c.handle)
+	assert.Nil(a.Start(context.Background()))
+	assert.Equal(map[string]int{"a": 4}, f.owners())
+
+	b := f.processor("b", c.handle)
+	assert.Nil(b.Start(context.Background()))
+	eventually(t, func() bool {
+		owners := f.owners()
+		return owners["a"] == 2 && owners["b"] == 2
+	})
+
+	// The leases released by b are taken by a
+	b.Stop()
+	eventually(t, func() bool { return f.owners()["a"] == 4 })
+	a.Stop()
+	assert.Equal(map[string]int{"": 4}, f.owners())
+
+	for i := 0; i < 4; i++ {
+		f.addChanges(strconv.Itoa(i), changes(strconv.Itoa(i))...)
+	}
+	assert.Empty(c.sorted())
+}
+
+func TestChangeFeedProcessorSplit(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{"0": changes("a", "b")})
+	defer f.Close()
+
+	c := &collector{}
+	p := f.processor("host", c.handle)
+	assert.Nil(p.Start(context.Background()))
+	eventually(t, func() bool { return len(c.sorted()) == 2 })
+
+	// Range 0 is split into 3 and 4, their changes include the changes of their parent
+	f.mu.Lock()
+	f.gone["0"] = subStatusSplit
+	f.ranges = append(f.ranges[1:],
+		PartitionKeyRange{PartitionKeyRangeID: "3", MinInclusive: "", MaxInclusive: "2A"},
+		PartitionKeyRange{PartitionKeyRangeID: "4", MinInclusive: "2A", MaxInclusive: "55"},
+	)
+	f.changes["3"] = changes("a", "b", "c")
+	f.changes["4"] = changes("x", "y", "d", "e")
+	f.mu.Unlock()
+
+	eventually(t, func() bool { return len(c.sorted()) == 5 })
+	p.Stop()
+	assert.Equal([]string{"a", "b", "c", "d", "e"}, c.sorted())
+
+	leases, err := p.Leases(context.Background())
+	assert.Nil(err)
+	var tokens []string
+	for _, l := range leases {
+		tokens = append(tokens, l.LeaseToken)
+	}
+	assert.Equal([]string{"3", "4", "1", "2"}, tokens)
+}
+
+func TestChangeFeedProcessorRenewal(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{"0": changes("a")})
+	f.ranges = f.ranges[:1]
+	defer f.Close()
+
+	// The lease is renewed while a batch takes longer than the expiration
+	var (
+		mu    sync.Mutex
+		hosts []string
+	)
+	handler := func(host string) ChangeFeedHandler {
+		return func(ctx context.Context, rangeID string, docs []json.RawMessage) error {
+			mu.Lock()
+			hosts = append(hosts, host)
+			mu.Unlock()
+			time.Sleep(300 * time.Millisecond)
+			return nil
+		}
+	}
+	a := f.processor("a", handler("a"))
+	a.options.LeaseExpirationInterval = 100 * time.Millisecond
+	assert.Nil(a.Start(context.Background()))
+	b := f.processor("b", handler("b"))
+	b.options.LeaseExpirationInterval = 100 * time.Millisecond
+	assert.Nil(b.Start(context.Background()))
+	time.Sleep(400 * time.Millisecond)
+	b.Stop()
+	a.Stop()
+	assert.Equal([]string{"a"}, hosts)
+}
+
+func TestChangeFeedProcessorLostLease(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{"0": changes("a")})
+	f.ranges = f.ranges[:1]
+	defer f.Close()
+
+	cancelled := make(chan struct{})
+	p := f.processor("a", func(ctx context.Context, rangeID string, docs []json.RawMessage) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	p.options.OnError = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	assert.Nil(p.Start(context.Background()))
+	defer p.Stop()
+	eventually(t, func() bool { return f.owners()["a"] == 1 })
+
+	// Another host takes the lease, the worker is cancelled at the next renewal
+	f.mu.Lock()
+	f.etag++
+	f.leases["coll.0"]["Owner"] = "b"
+	f.leases["coll.0"]["_etag"] = strconv.Itoa(f.etag)
+	f.mu.Unlock()
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		assert.Fail("the worker should be cancelled")
+	}
+	eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.workers) == 0
+	})
+	assert.Equal(map[string]int{"b": 1}, f.owners())
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.NotEmpty(errs) {
+		assert.True(errors.Is(errs[0], ErrPreconditionFailed))
+	}
+}
+
+func TestChangeFeedProcessorSplitStaleRouting(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeChangeFeed(map[string][]string{"0": changes("a")})
+	f.ranges = f.ranges[:1]
+	defer f.Close()
+
+	c := &collector{}
+	p := f.processor("host", c.handle)
+	assert.Nil(p.Start(context.Background()))
+	defer p.Stop()
+	eventually(t, func() bool { return len(c.sorted()) == 1 })
+
+	// The range is gone, but the routing doesn't list its children yet
+	f.mu.Lock()
+	f.gone["0"] = subStatusSplit
+	f.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	leases, err := p.Leases(context.Background())
+	assert.Nil(err)
+	if assert.Equal(1, len(leases), "the lease of the parent is kept") {
+		assert.Equal("0", leases[0].LeaseToken)
+	}
+
+	f.mu.Lock()
+	f.ranges = []PartitionKeyRange{
+		{PartitionKeyRangeID: "1", MinInclusive: "", MaxInclusive: "2A"},
+		{PartitionKeyRangeID: "2", MinInclusive: "2A", MaxInclusive: "55"},
+	}
+	f.changes["1"] = changes("a", "b")
+	// The children continue from the continuation of their parent
+	f.changes["2"] = changes("x", "c")
+	f.mu.Unlock()
+	eventually(t, func() bool { return len(c.sorted()) == 3 })
+	assert.Equal([]string{"a", "b", "c"}, c.sorted())
+}
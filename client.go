@@ -2,8 +2,12 @@ package documentdb
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 type Clienter interface {
@@ -14,6 +18,21 @@ type Clienter interface {
 	Upsert(link string, body, ret interface{}, opts ...CallOption) (*Response, error)
 	Replace(link string, body, ret interface{}, opts ...CallOption) (*Response, error)
 	Execute(link string, body, ret interface{}, opts ...CallOption) (*Response, error)
+
+	ReadWithContext(ctx context.Context, link string, ret interface{}, opts ...CallOption) (*Response, error)
+	DeleteWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error)
+	QueryWithContext(ctx context.Context, link string, query *Query, ret interface{}, opts ...CallOption) (*Response, error)
+	CreateWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error)
+	UpsertWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error)
+	ReplaceWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error)
+	ExecuteWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error)
+}
+
+// Streamer is implemented by the clients that can read a resource without
+// decoding its body. It's optional, the media of attachments are read through it
+type Streamer interface {
+	Stream(link string, opts ...CallOption) (io.ReadCloser, *Response, error)
+	StreamWithContext(ctx context.Context, link string, opts ...CallOption) (io.ReadCloser, *Response, error)
 }
 
 type Client struct {
@@ -21,6 +40,14 @@ type Client struct {
 	Config *Config
 	http.Client
 	UserAgent string
+	// SessionContainer, if set, records the session tokens of the responses
+	// and attaches them to the subsequent reads
+	SessionContainer *SessionContainer
+
+	// locations is set when endpoint discovery is enabled, locationsErr
+	// when its configuration is not valid
+	locations    *locationCache
+	locationsErr error
 }
 
 func (c *Client) apply(r *Request, opts []CallOption) (err error) {
@@ -38,9 +65,14 @@ func (c *Client) apply(r *Request, opts []CallOption) (err error) {
 
 // Read resource by self link
 func (c *Client) Read(link string, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.ReadWithContext(context.Background(), link, ret, opts...)
+}
+
+// ReadWithContext reads resource by self link, the request is bound to ctx
+func (c *Client) ReadWithContext(ctx context.Context, link string, ret interface{}, opts ...CallOption) (*Response, error) {
 	buf := buffers.Get().(*bytes.Buffer)
 	buf.Reset()
-	res, err := c.method(http.MethodGet, link, expectStatusCode(http.StatusOK), ret, buf, opts...)
+	res, err := c.method(ctx, http.MethodGet, link, expectStatusCode(http.StatusOK), ret, buf, opts...)
 
 	buffers.Put(buf)
 
@@ -49,11 +81,21 @@ func (c *Client) Read(link string, ret interface{}, opts ...CallOption) (*Respon
 
 // Delete resource by self link
 func (c *Client) Delete(link string, opts ...CallOption) (*Response, error) {
-	return c.method(http.MethodDelete, link, expectStatusCode(http.StatusNoContent), nil, &bytes.Buffer{}, opts...)
+	return c.DeleteWithContext(context.Background(), link, opts...)
+}
+
+// DeleteWithContext deletes resource by self link, the request is bound to ctx
+func (c *Client) DeleteWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.method(ctx, http.MethodDelete, link, expectStatusCode(http.StatusNoContent), nil, &bytes.Buffer{}, opts...)
 }
 
 // Query resource
 func (c *Client) Query(link string, query *Query, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.QueryWithContext(context.Background(), link, query, ret, opts...)
+}
+
+// QueryWithContext queries resource, the request is bound to ctx
+func (c *Client) QueryWithContext(ctx context.Context, link string, query *Query, ret interface{}, opts ...CallOption) (*Response, error) {
 	var (
 		err error
 		req *http.Request
@@ -62,12 +104,15 @@ func (c *Client) Query(link string, query *Query, ret interface{}, opts ...CallO
 	buf.Reset()
 	defer buffers.Put(buf)
 
+	if err = query.Validate(); err != nil {
+		return nil, err
+	}
 	if err = Serialization.EncoderFactory(buf).Encode(query); err != nil {
 		return nil, err
 
 	}
 
-	req, err = http.NewRequest(http.MethodPost, c.Url+"/"+link, buf)
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, c.Url+"/"+link, buf)
 	if err != nil {
 		return nil, err
 	}
@@ -84,49 +129,96 @@ func (c *Client) Query(link string, query *Query, ret interface{}, opts ...CallO
 
 // Create resource
 func (c *Client) Create(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.CreateWithContext(context.Background(), link, body, ret, opts...)
+}
+
+// CreateWithContext creates resource, the request is bound to ctx
+func (c *Client) CreateWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
 	data, err := stringify(body)
 	if err != nil {
 		return nil, err
 	}
 	buf := bytes.NewBuffer(data)
-	return c.method(http.MethodPost, link, expectStatusCode(http.StatusCreated), ret, buf, opts...)
+	return c.method(ctx, http.MethodPost, link, expectStatusCode(http.StatusCreated), ret, buf, opts...)
 }
 
 // Upsert resource
 func (c *Client) Upsert(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.UpsertWithContext(context.Background(), link, body, ret, opts...)
+}
+
+// UpsertWithContext upserts resource, the request is bound to ctx
+func (c *Client) UpsertWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
 	opts = append(opts, Upsert())
 	data, err := stringify(body)
 	if err != nil {
 		return nil, err
 	}
 	buf := bytes.NewBuffer(data)
-	return c.method(http.MethodPost, link, expectStatusCodeXX(http.StatusOK), ret, buf, opts...)
+	return c.method(ctx, http.MethodPost, link, expectStatusCodeXX(http.StatusOK), ret, buf, opts...)
 }
 
 // Replace resource
 func (c *Client) Replace(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.ReplaceWithContext(context.Background(), link, body, ret, opts...)
+}
+
+// ReplaceWithContext replaces resource, the request is bound to ctx
+func (c *Client) ReplaceWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
 	data, err := stringify(body)
 	if err != nil {
 		return nil, err
 	}
 	buf := bytes.NewBuffer(data)
-	return c.method(http.MethodPut, link, expectStatusCode(http.StatusOK), ret, buf, opts...)
+	return c.method(ctx, http.MethodPut, link, expectStatusCode(http.StatusOK), ret, buf, opts...)
 }
 
-// Replace resource
-// TODO: DRY, move to methods instead of actions(POST, PUT, ...)
+// Execute resource
 func (c *Client) Execute(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.ExecuteWithContext(context.Background(), link, body, ret, opts...)
+}
+
+// ExecuteWithContext executes resource, the request is bound to ctx
+// TODO: DRY, move to methods instead of actions(POST, PUT, ...)
+func (c *Client) ExecuteWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
 	data, err := stringify(body)
 	if err != nil {
 		return nil, err
 	}
 	buf := bytes.NewBuffer(data)
-	return c.method(http.MethodPost, link, expectStatusCode(http.StatusOK), ret, buf, opts...)
+	return c.method(ctx, http.MethodPost, link, expectStatusCode(http.StatusOK), ret, buf, opts...)
+}
+
+// Stream reads resource by self link, without decoding its body
+func (c *Client) Stream(link string, opts ...CallOption) (io.ReadCloser, *Response, error) {
+	return c.StreamWithContext(context.Background(), link, opts...)
+}
+
+// StreamWithContext reads resource by self link without decoding its body, the request
+// is bound to ctx. The caller is responsible for closing the returned body
+func (c *Client) StreamWithContext(ctx context.Context, link string, opts ...CallOption) (io.ReadCloser, *Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Url+"/"+link, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := ResourceRequest(link, req)
+	if err = c.apply(r, opts); err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.send(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, nil, newRequestError(resp)
+	}
+	return resp.Body, &Response{Header: resp.Header, StatusCode: resp.StatusCode}, nil
 }
 
 // Private generic method resource
-func (c *Client) method(method string, link string, validator statusCodeValidatorFunc, ret interface{}, body *bytes.Buffer, opts ...CallOption) (*Response, error) {
-	req, err := http.NewRequest(method, c.Url+"/"+link, body)
+func (c *Client) method(ctx context.Context, method string, link string, validator statusCodeValidatorFunc, ret interface{}, body *bytes.Buffer, opts ...CallOption) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.Url+"/"+link, body)
 	if err != nil {
 		return nil, err
 	}
@@ -142,20 +234,87 @@ func (c *Client) method(method string, link string, validator statusCodeValidato
 
 // Private Do function, DRY
 func (c *Client) do(r *Request, validator statusCodeValidatorFunc, data interface{}) (*Response, error) {
-	resp, err := c.Do(r.Request)
+	resp, err := c.send(r)
 	if err != nil {
 		return nil, err
 	}
-	if !validator(resp.StatusCode) {
-		err = &RequestError{}
-		readJson(resp.Body, &err)
-		return nil, err
+	return c.handle(resp, validator, data)
+}
+
+// send sends the request and returns the raw response.
+// Throttled requests are sent again according to the configured RetryPolicy,
+// and with endpoint discovery, requests fail over to the next region
+func (c *Client) send(r *Request) (*http.Response, error) {
+	if c.locationsErr != nil {
+		return nil, c.locationsErr
 	}
+	var (
+		waited time.Duration
+		tried  []*url.URL
+	)
+	c.applySession(r)
+	for attempt := 1; ; attempt++ {
+		endpoint := c.route(r, tried)
+		resp, err := c.Do(r.Request)
+		if err != nil {
+			if !c.failover(r, endpoint, nil, err, append(tried, endpoint)) {
+				return nil, err
+			}
+			tried = append(tried, endpoint)
+		} else {
+			if c.SessionContainer != nil {
+				c.SessionContainer.Set(r.link, resp.Header.Get(HeaderSessionToken))
+			}
+			if c.failover(r, endpoint, resp, nil, append(tried, endpoint)) {
+				tried = append(tried, endpoint)
+				discard(resp)
+			} else {
+				wait, retry := c.Config.RetryPolicy.backoff(attempt, waited, resp)
+				if !retry || r.noRetry {
+					return resp, nil
+				}
+				discard(resp)
+				if err = sleep(r.Context(), wait); err != nil {
+					return nil, err
+				}
+				waited += wait
+			}
+		}
+		if err = r.rewind(c.Config, c.UserAgent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// discard drains and closes the body of a response that is not used,
+// so the connection can be reused
+func discard(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// applySession attaches the known session token to read requests that
+// didn't set one explicitly
+func (c *Client) applySession(r *Request) {
+	if c.SessionContainer == nil || !r.isRead() || r.Header.Get(HeaderSessionToken) != "" {
+		return
+	}
+	if token := c.SessionContainer.Get(r.link); token != "" {
+		r.Header.Set(HeaderSessionToken, token)
+	}
+}
+
+// handle validates the response status code and decodes its body
+func (c *Client) handle(resp *http.Response, validator statusCodeValidatorFunc, data interface{}) (*Response, error) {
 	defer resp.Body.Close()
+	if !validator(resp.StatusCode) {
+		return nil, newRequestError(resp)
+	}
+	res := &Response{Header: resp.Header, StatusCode: resp.StatusCode}
 	if data == nil {
-		return nil, nil
+		return res, nil
 	}
-	return &Response{resp.Header}, readJson(resp.Body, data)
+	return res, readJson(resp.Body, data)
 }
 
 // Read json response to given interface(struct, map, ..)
@@ -1,11 +1,15 @@
 package documentdb
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -60,6 +64,25 @@ func ServerFactory(resp ...interface{}) *MockServer {
 	return s
 }
 
+// HandlerFactory creates a mock server that delegates the requests to handler,
+// for the fakes that keep a state between the requests (e.g: regions, leases)
+func HandlerFactory(handler http.HandlerFunc) *MockServer {
+	s := &MockServer{}
+	s.Server = httptest.NewServer(handler)
+	return s
+}
+
+// DB returns a client of the server, signed with a test key
+func (s *MockServer) DB() *DocumentDB {
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+	return &DocumentDB{client, client.Config}
+}
+
+// serveRanges answers a read of the partition key ranges of a collection
+func serveRanges(w http.ResponseWriter, ranges []PartitionKeyRange) {
+	json.NewEncoder(w).Encode(queryPartitionKeyRangesRequest{Ranges: ranges})
+}
+
 func TestRead(t *testing.T) {
 	assert := assert.New(t)
 	s := ServerFactory(`{"_colls": "colls"}`, 500)
@@ -95,6 +118,21 @@ func TestReadWithUserAgent(t *testing.T) {
 	assert.Nil(err, "err should be nil")
 }
 
+func TestReadWithContext(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"_colls": "colls"}`)
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var db Database
+	_, err := client.ReadWithContext(ctx, "/dbs/b7NTAS==/", &db)
+	assert.Equal(context.Canceled, errors.Unwrap(err), "Should fail with the context error")
+	assert.Equal(db.Colls, "", "Should not fill the fields")
+}
+
 func TestQuery(t *testing.T) {
 	assert := assert.New(t)
 	s := ServerFactory(`{"_colls": "colls"}`, 500)
@@ -211,3 +249,104 @@ func TestExecute(t *testing.T) {
 	_, err = client.Execute("dbs", tDoc, &doc)
 	assert.Equal(err.Error(), "500, DocumentDB error")
 }
+
+func TestRetryThrottled(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		calls    int
+		throttle = 2
+		bodies   []string
+		dates    []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		dates = append(dates, r.Header.Get(HeaderXDate))
+		if calls <= throttle {
+			w.Header().Set(HeaderRetryAfter, "5")
+			http.Error(w, `{"code": "429", "message": "Request rate is too large"}`, http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintln(w, `{"id": "9"}`)
+	}))
+	defer s.Close()
+	config := NewConfig(&Key{Key: "YXJpZWwNCg=="}).WithRetryPolicy(RetryPolicy{MaxAttempts: 3})
+	client := &Client{Url: s.URL, Config: config}
+
+	var doc Document
+	_, err := client.Create("dbs/colls/docs", `{"id": "9"}`, &doc)
+	assert.Nil(err, "err should be nil")
+	assert.Equal(3, calls, "Should retry throttled requests")
+	assert.Equal([]string{`{"id": "9"}`, `{"id": "9"}`, `{"id": "9"}`}, bodies, "Should replay the body")
+	assert.Len(dates, 3)
+	assert.Equal("9", doc.Id)
+
+	// Stop when attempts are exhausted
+	calls, throttle = 0, 3
+	_, err = client.Create("dbs/colls/docs", `{"id": "9"}`, &doc, PartitionKey("9"))
+	assert.Equal(err.Error(), "429, Request rate is too large")
+	assert.Equal(3, calls)
+
+	// Stop when the wait exceeds the max
+	calls = 0
+	config.RetryPolicy.MaxWait = time.Millisecond
+	_, err = client.Create("dbs/colls/docs", `{"id": "9"}`, &doc)
+	assert.Equal(err.Error(), "429, Request rate is too large")
+	assert.Equal(1, calls)
+}
+
+func TestRequestErrorFromResponse(t *testing.T) {
+	assert := assert.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderSubStatus, "1002")
+		w.Header().Set(HeaderActivityID, "activity")
+		w.Header().Set(HeaderRequestCharge, "1.24")
+		w.Header().Set(HeaderRetryAfter, "10")
+		http.Error(w, `{"code": "Gone", "message": "partition key range is gone"}`, http.StatusGone)
+	}))
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+
+	var db Database
+	_, err := client.Read("/dbs/b7NTAS==/", &db)
+	assert.True(errors.Is(err, ErrGone))
+	assert.False(errors.Is(err, ErrNotFound))
+
+	var reqErr *RequestError
+	assert.True(errors.As(err, &reqErr))
+	assert.Equal(RequestError{
+		Code:          "Gone",
+		Message:       "partition key range is gone",
+		StatusCode:    http.StatusGone,
+		SubStatus:     1002,
+		ActivityID:    "activity",
+		RequestCharge: 1.24,
+		RetryAfter:    10 * time.Millisecond,
+	}, *reqErr)
+}
+
+func TestSessionContainerHeaders(t *testing.T) {
+	assert := assert.New(t)
+	var sent []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Header.Get(HeaderSessionToken))
+		w.Header().Set(HeaderSessionToken, "0:1#12")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		fmt.Fprintln(w, `{"id": "9"}`)
+	}))
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="}), SessionContainer: NewSessionContainer()}
+
+	var doc Document
+	_, err := client.Create("dbs/db/colls/coll/docs", `{"id": "9"}`, &doc)
+	assert.Nil(err)
+	_, err = client.Read("dbs/db/colls/coll/docs/9", &doc)
+	assert.Nil(err)
+	_, err = client.Read("dbs/db/colls/coll/docs/9", &doc, SessionToken("0:1#1"))
+	assert.Nil(err)
+	assert.Equal([]string{"", "0:1#12", "0:1#1"}, sent, "Should attach the recorded token to reads")
+}
@@ -0,0 +1,62 @@
+package documentdb
+
+import "context"
+
+// Read conflict by self link
+func (c *DocumentDB) ReadConflict(link string, opts ...CallOption) (conflict *Conflict, err error) {
+	conflict, _, err = c.ReadConflictWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadConflictWithContext reads conflict by self link, the request is bound to ctx
+func (c *DocumentDB) ReadConflictWithContext(ctx context.Context, link string, opts ...CallOption) (conflict *Conflict, res *Response, err error) {
+	res, err = c.client.ReadWithContext(ctx, link, &conflict, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Read all conflicts by collection self link
+func (c *DocumentDB) ReadConflicts(coll string, opts ...CallOption) (conflicts []Conflict, err error) {
+	conflicts, _, err = c.ReadConflictsWithContext(context.Background(), coll, opts...)
+	return
+}
+
+// ReadConflictsWithContext reads all conflicts by collection self link, the request is bound to ctx
+func (c *DocumentDB) ReadConflictsWithContext(ctx context.Context, coll string, opts ...CallOption) (conflicts []Conflict, res *Response, err error) {
+	return c.QueryConflictsWithContext(ctx, coll, nil, opts...)
+}
+
+// Read all collection `conflicts` that satisfy a query
+func (c *DocumentDB) QueryConflicts(coll string, query *Query, opts ...CallOption) (conflicts []Conflict, err error) {
+	conflicts, _, err = c.QueryConflictsWithContext(context.Background(), coll, query, opts...)
+	return
+}
+
+// QueryConflictsWithContext reads all collection `conflicts` that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryConflictsWithContext(ctx context.Context, coll string, query *Query, opts ...CallOption) (conflicts []Conflict, res *Response, err error) {
+	data := struct {
+		Conflicts []Conflict `json:"Conflicts,omitempty"`
+		Count     int        `json:"_count,omitempty"`
+	}{}
+	if query != nil {
+		res, err = c.client.QueryWithContext(ctx, coll+"conflicts/", query, &data, opts...)
+	} else {
+		res, err = c.client.ReadWithContext(ctx, coll+"conflicts/", &data, opts...)
+	}
+	if conflicts = data.Conflicts; err != nil {
+		conflicts = nil
+	}
+	return
+}
+
+// Delete conflict, i.e: mark it as resolved
+func (c *DocumentDB) DeleteConflict(link string, opts ...CallOption) (*Response, error) {
+	return c.DeleteConflictWithContext(context.Background(), link, opts...)
+}
+
+// DeleteConflictWithContext deletes conflict, the request is bound to ctx
+func (c *DocumentDB) DeleteConflictWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.client.DeleteWithContext(ctx, link, opts...)
+}
@@ -0,0 +1,63 @@
+package documentdb
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReadConflict(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "conflict_link", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadConflict("conflict_link")
+	client.AssertCalled(t, "Read", "conflict_link", mock.Anything, mock.Anything)
+}
+
+func TestReadConflicts(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "coll_link/conflicts/", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadConflicts("coll_link/")
+	client.AssertCalled(t, "Read", "coll_link/conflicts/", mock.Anything, mock.Anything)
+}
+
+func TestQueryConflicts(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	q := NewQuery("SELECT * FROM ROOT r WHERE r.operationType = 'replace'")
+	client.On("Query", "coll_link/conflicts/", q).Return(nil)
+	c.QueryConflicts("coll_link/", q)
+	client.AssertCalled(t, "Query", "coll_link/conflicts/", q)
+}
+
+func TestDeleteConflict(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Delete", "conflict_link").Return(nil, nil)
+	c.DeleteConflict("conflict_link")
+	client.AssertCalled(t, "Delete", "conflict_link")
+}
+
+func TestConflictResolutionPolicy(t *testing.T) {
+	assert := assert.New(t)
+	b, err := json.Marshal(&Collection{
+		Resource: Resource{Id: "coll"},
+		ConflictResolutionPolicy: &ConflictResolutionPolicy{
+			Mode:                   ConflictLastWriterWins,
+			ConflictResolutionPath: "/updatedAt",
+		},
+	})
+	assert.Nil(err)
+	assert.Contains(string(b), `"conflictResolutionPolicy":{"mode":"LastWriterWins","conflictResolutionPath":"/updatedAt"}`)
+
+	var conflict Conflict
+	err = json.Unmarshal([]byte(`{"id":"c1","resourceType":"document","operationType":"create","resourceId":"b5NCAJk5AQA=","content":"{\"id\":\"1\"}"}`), &conflict)
+	assert.Nil(err)
+	assert.Equal("document", conflict.ResourceType)
+	assert.Equal("create", conflict.OperationType)
+	assert.Equal("b5NCAJk5AQA=", conflict.SourceResourceId)
+	assert.Equal(`{"id":"1"}`, conflict.Content)
+}
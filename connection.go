@@ -0,0 +1,91 @@
+package documentdb
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+const (
+	// EmulatorConnectionString is the well-known connection string of the local emulator
+	EmulatorConnectionString = "AccountEndpoint=https://localhost:8081/;AccountKey=C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw==;"
+
+	// Environment variables read by NewConfigFromEnv
+	EnvConnectionString = "COSMOS_CONNECTION_STRING"
+	EnvEndpoint         = "COSMOS_ENDPOINT"
+	EnvKey              = "COSMOS_KEY"
+)
+
+// ParseConnectionString parses a connection string in the
+// "AccountEndpoint={url};AccountKey={key};" format, and validates the key.
+func ParseConnectionString(connectionString string) (endpoint string, key *Key, err error) {
+	for _, part := range strings.Split(connectionString, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// The key is base64 encoded, and may contain "="
+		i := strings.IndexByte(part, '=')
+		if i == -1 {
+			return "", nil, errors.New("invalid connection string, expected key=value pairs")
+		}
+		switch name, value := part[:i], part[i+1:]; {
+		case strings.EqualFold(name, "AccountEndpoint"):
+			endpoint = value
+		case strings.EqualFold(name, "AccountKey"):
+			key = NewKey(value)
+		}
+	}
+	return newEndpointKey(endpoint, key)
+}
+
+// NewConfigFromConnectionString returns the endpoint and the config of a
+// connection string, for callers that need to customize the config.
+func NewConfigFromConnectionString(connectionString string) (string, *Config, error) {
+	endpoint, key, err := ParseConnectionString(connectionString)
+	if err != nil {
+		return "", nil, err
+	}
+	return endpoint, NewConfig(key), nil
+}
+
+// NewConfigFromEnv returns the endpoint and the config from the environment,
+// either COSMOS_CONNECTION_STRING, or COSMOS_ENDPOINT and COSMOS_KEY.
+func NewConfigFromEnv() (string, *Config, error) {
+	if connectionString := os.Getenv(EnvConnectionString); connectionString != "" {
+		return NewConfigFromConnectionString(connectionString)
+	}
+	var key *Key
+	if v := os.Getenv(EnvKey); v != "" {
+		key = NewKey(v)
+	}
+	endpoint, key, err := newEndpointKey(os.Getenv(EnvEndpoint), key)
+	if err != nil {
+		return "", nil, err
+	}
+	return endpoint, NewConfig(key), nil
+}
+
+// NewFromConnectionString creates DocumentDBClient from a connection string
+func NewFromConnectionString(connectionString string) (*DocumentDB, error) {
+	endpoint, config, err := NewConfigFromConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	return New(endpoint, config), nil
+}
+
+// newEndpointKey validates the endpoint and the key
+func newEndpointKey(endpoint string, key *Key) (string, *Key, error) {
+	if endpoint == "" {
+		return "", nil, errors.New("missing account endpoint")
+	}
+	if key == nil || key.Key == "" {
+		return "", nil, errors.New("missing account key")
+	}
+	if _, err := key.Salt(); err != nil {
+		return "", nil, err
+	}
+	// Links are joined to the endpoint with a "/"
+	return strings.TrimSuffix(endpoint, "/"), key, nil
+}
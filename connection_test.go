@@ -0,0 +1,65 @@
+package documentdb
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConnectionString(t *testing.T) {
+	assert := assert.New(t)
+
+	endpoint, key, err := ParseConnectionString(EmulatorConnectionString)
+	assert.Nil(err)
+	assert.Equal("https://localhost:8081", endpoint)
+	assert.Equal("C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw==", key.Key)
+
+	endpoint, key, err = ParseConnectionString(" accountkey=YXJpZWwNCg==; AccountEndpoint=https://account.documents.azure.com:443/")
+	assert.Nil(err)
+	assert.Equal("https://account.documents.azure.com:443", endpoint)
+	assert.Equal("YXJpZWwNCg==", key.Key)
+
+	_, _, err = ParseConnectionString("AccountKey=YXJpZWwNCg==;")
+	assert.EqualError(err, "missing account endpoint")
+
+	_, _, err = ParseConnectionString("AccountEndpoint=https://localhost:8081/;")
+	assert.EqualError(err, "missing account key")
+
+	_, _, err = ParseConnectionString("AccountEndpoint=https://localhost:8081/;AccountKey=not base64;")
+	assert.EqualError(err, "base64 input is corrupt, check CosmosDB key.")
+
+	_, _, err = ParseConnectionString("AccountEndpoint")
+	assert.Error(err)
+}
+
+func TestNewFromConnectionString(t *testing.T) {
+	assert := assert.New(t)
+	client, err := NewFromConnectionString(EmulatorConnectionString)
+	assert.Nil(err)
+	assert.Equal("https://localhost:8081", client.client.(*Client).Url)
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	assert := assert.New(t)
+	defer os.Unsetenv(EnvConnectionString)
+	defer os.Unsetenv(EnvEndpoint)
+	defer os.Unsetenv(EnvKey)
+
+	os.Setenv(EnvEndpoint, "https://account.documents.azure.com/")
+	os.Setenv(EnvKey, "YXJpZWwNCg==")
+	endpoint, config, err := NewConfigFromEnv()
+	assert.Nil(err)
+	assert.Equal("https://account.documents.azure.com", endpoint)
+	assert.Equal("YXJpZWwNCg==", config.MasterKey.Key)
+
+	os.Setenv(EnvConnectionString, EmulatorConnectionString)
+	endpoint, _, err = NewConfigFromEnv()
+	assert.Nil(err)
+	assert.Equal("https://localhost:8081", endpoint)
+
+	os.Unsetenv(EnvConnectionString)
+	os.Unsetenv(EnvKey)
+	_, _, err = NewConfigFromEnv()
+	assert.EqualError(err, "missing account key")
+}
@@ -0,0 +1,489 @@
+package documentdb
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// supportedQueryFeatures are the features the query engine handles client-side,
+// the gateway rejects query plans that need other features
+const supportedQueryFeatures = "Aggregate, CompositeAggregate, Distinct, MultipleOrderBy, OffsetAndLimit, OrderBy, Top, GroupBy, MultipleAggregates, NonValueAggregate"
+
+// orderByFilter is the placeholder of the rewritten ORDER BY queries, it's used
+// to resume a query after a continuation
+const orderByFilter = "{documentdb-formattableorderbyquery-filter}"
+
+// queryPlan describes how to run a query across partitions
+type queryPlan struct {
+	QueryInfo   queryInfo    `json:"queryInfo"`
+	QueryRanges []queryRange `json:"queryRanges"`
+}
+
+type queryInfo struct {
+	DistinctType       string   `json:"distinctType"`
+	Top                *int     `json:"top"`
+	Offset             *int     `json:"offset"`
+	Limit              *int     `json:"limit"`
+	OrderBy            []string `json:"orderBy"`
+	OrderByExpressions []string `json:"orderByExpressions"`
+	GroupByExpressions []string `json:"groupByExpressions"`
+	GroupByAliases     []string `json:"groupByAliases"`
+	Aggregates         []string `json:"aggregates"`
+	// GroupByAliasToAggregateType maps the aliases of the projection to their
+	// aggregate, or to an empty string for the group keys
+	GroupByAliasToAggregateType map[string]string `json:"groupByAliasToAggregateType"`
+	RewrittenQuery              string            `json:"rewrittenQuery"`
+	HasSelectValue              bool              `json:"hasSelectValue"`
+}
+
+// queryRange is an effective partition key range targeted by a query
+type queryRange struct {
+	Min            string `json:"min"`
+	Max            string `json:"max"`
+	IsMinInclusive bool   `json:"isMinInclusive"`
+	IsMaxInclusive bool   `json:"isMaxInclusive"`
+}
+
+// overlaps reports whether the query range overlaps [r.MinInclusive, r.MaxInclusive),
+// the bounds of the query range are inclusive or not according to its flags
+func (q queryRange) overlaps(r PartitionKeyRange) bool {
+	// The bounds of the intersection
+	min, minInclusive := q.Min, q.IsMinInclusive
+	if r.MinInclusive > q.Min {
+		min, minInclusive = r.MinInclusive, true
+	}
+	max, maxInclusive := q.Max, q.IsMaxInclusive
+	if r.MaxInclusive != "" && r.MaxInclusive <= q.Max {
+		max, maxInclusive = r.MaxInclusive, false
+	}
+	return min < max || (min == max && minInclusive && maxInclusive)
+}
+
+// queryPlanRequest sets the headers of a query plan request
+func queryPlanRequest() CallOption {
+	return func(r *Request) error {
+		r.Header.Set(HeaderIsQueryPlanRequest, "True")
+		r.Header.Set(HeaderSupportedQueryFeatures, supportedQueryFeatures)
+		r.Header.Set(HeaderQueryVersion, "1.4")
+		return nil
+	}
+}
+
+// Query documents across all the partitions of a collection. Unlike QueryDocuments
+// with CrossPartition, ORDER BY, TOP, OFFSET/LIMIT, DISTINCT, GROUP BY and aggregates
+// are evaluated on the merged results of all the partitions. All the pages are read
+// and decoded into docs, the request charge of the response is the total charge
+func (c *DocumentDB) QueryDocumentsCrossPartition(coll string, query *Query, docs interface{}, opts ...CallOption) (*Response, error) {
+	return c.QueryDocumentsCrossPartitionWithContext(context.Background(), coll, query, docs, opts...)
+}
+
+// QueryDocumentsCrossPartitionWithContext queries documents across all the partitions
+// of a collection, the requests are bound to ctx
+func (c *DocumentDB) QueryDocumentsCrossPartitionWithContext(ctx context.Context, coll string, query *Query, docs interface{}, opts ...CallOption) (*Response, error) {
+	q, err := c.newCrossPartitionQuery(ctx, coll, query, opts)
+	if err != nil {
+		return q.response(), err
+	}
+	var results [][]byte
+	for {
+		item, ok, err := q.stream.next()
+		if err != nil {
+			return q.response(), err
+		}
+		if !ok {
+			break
+		}
+		results = append(results, item)
+	}
+	data := make([]byte, 0, 2)
+	data = append(data, '[')
+	data = append(data, bytes.Join(results, []byte{','})...)
+	data = append(data, ']')
+	return q.response(), Serialization.Unmarshal(data, docs)
+}
+
+// crossPartitionQuery is a query running on the partition key ranges it targets
+type crossPartitionQuery struct {
+	plan   *queryPlan
+	ranges []*rangeQuery
+	stream resultStream
+	charge float64
+}
+
+func (c *DocumentDB) newCrossPartitionQuery(ctx context.Context, coll string, query *Query, opts []CallOption) (*crossPartitionQuery, error) {
+	q, err := c.prepareCrossPartitionQuery(ctx, coll, query, opts)
+	if err != nil {
+		return q, err
+	}
+	q.stream, err = q.pipeline()
+	return q, err
+}
+
+// prepareCrossPartitionQuery fetches the query plan and creates the queries
+// of the partition key ranges it targets
+func (c *DocumentDB) prepareCrossPartitionQuery(ctx context.Context, coll string, query *Query, opts []CallOption) (*crossPartitionQuery, error) {
+	q := &crossPartitionQuery{}
+	var plan *queryPlan
+	res, err := c.client.QueryWithContext(ctx, coll+"docs/", query, &plan, append(opts[:len(opts):len(opts)], CrossPartition(), queryPlanRequest())...)
+	q.addCharge(res)
+	if err != nil {
+		return q, err
+	}
+	q.plan = plan
+
+	ranges, err := c.targetRanges(ctx, coll, plan.QueryRanges, q)
+	if err != nil {
+		return q, err
+	}
+
+	text := query.Query
+	if plan.QueryInfo.RewrittenQuery != "" {
+		text = strings.Replace(plan.QueryInfo.RewrittenQuery, orderByFilter, "true", -1)
+	}
+	rewritten := NewQuery(text, query.Parameters...)
+	for _, r := range ranges {
+		q.ranges = append(q.ranges, &rangeQuery{
+			db:      c,
+			ctx:     ctx,
+			coll:    coll,
+			query:   rewritten,
+			rangeID: r.PartitionKeyRangeID,
+			min:     r.MinInclusive,
+			max:     r.MaxInclusive,
+			opts:    opts,
+			charge:  q.addCharge,
+		})
+	}
+	return q, nil
+}
+
+// targetRanges returns the partition key ranges that overlap the query ranges, ordered by their min
+func (c *DocumentDB) targetRanges(ctx context.Context, coll string, queryRanges []queryRange, q *crossPartitionQuery) ([]PartitionKeyRange, error) {
+	var (
+		all          []PartitionKeyRange
+		continuation string
+	)
+	for {
+		ranges, res, err := c.QueryPartitionKeyRangesWithContext(ctx, coll, nil, Continuation(continuation))
+		q.addCharge(res)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, ranges...)
+		if res == nil || res.Continuation() == "" {
+			break
+		}
+		continuation = res.Continuation()
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].MinInclusive < all[j].MinInclusive
+	})
+	if len(queryRanges) == 0 {
+		return all, nil
+	}
+	var target []PartitionKeyRange
+	for _, r := range all {
+		for _, qr := range queryRanges {
+			if qr.overlaps(r) {
+				target = append(target, r)
+				break
+			}
+		}
+	}
+	return target, nil
+}
+
+func (q *crossPartitionQuery) addCharge(res *Response) {
+	if res != nil && res.Header != nil {
+		q.charge += res.RequestCharge()
+	}
+}
+
+// response returns the response of the whole query
+func (q *crossPartitionQuery) response() *Response {
+	res := &Response{Header: http.Header{}, StatusCode: http.StatusOK}
+	res.Header.Set(HeaderRequestCharge, strconv.FormatFloat(q.charge, 'f', -1, 64))
+	return res
+}
+
+// unordered reports whether the results of the ranges can be returned in any
+// order, without being merged, i.e: the query is a plain filter or projection
+func (info queryInfo) unordered() bool {
+	return len(info.OrderBy) == 0 && len(info.Aggregates) == 0 && len(info.GroupByExpressions) == 0 &&
+		len(info.GroupByAliasToAggregateType) == 0 && (info.DistinctType == "" || info.DistinctType == "None") &&
+		info.Top == nil && info.Offset == nil && info.Limit == nil
+}
+
+// pipeline chains the stages the query plan needs
+func (q *crossPartitionQuery) pipeline() (resultStream, error) {
+	info := q.plan.QueryInfo
+	var s resultStream
+	switch {
+	case len(info.GroupByExpressions) > 0 || len(info.GroupByAliasToAggregateType) > 0:
+		g, err := groupBy(q.concat(), info)
+		if err != nil {
+			return nil, err
+		}
+		s = g
+	case len(info.Aggregates) > 0:
+		a, err := aggregate(q.concat(), info.Aggregates)
+		if err != nil {
+			return nil, err
+		}
+		s = a
+	case len(info.OrderBy) > 0:
+		s = q.orderBy()
+	default:
+		s = q.concat()
+	}
+	if info.DistinctType != "" && info.DistinctType != "None" {
+		s = &distinctStream{source: s, seen: make(map[string]bool)}
+	}
+	if info.Offset != nil || info.Limit != nil {
+		t := &takeStream{source: s, skip: 0, take: -1}
+		if info.Offset != nil {
+			t.skip = *info.Offset
+		}
+		if info.Limit != nil {
+			t.take = *info.Limit
+		}
+		s = t
+	}
+	if info.Top != nil {
+		s = &takeStream{source: s, take: *info.Top}
+	}
+	return s, nil
+}
+
+// resultStream is a stage of the query pipeline
+type resultStream interface {
+	// next returns the next result, ok is false when there are no more results
+	next() (item json.RawMessage, ok bool, err error)
+}
+
+// rangeQuery pages through the results of a query on one partition key range
+type rangeQuery struct {
+	db           *DocumentDB
+	ctx          context.Context
+	coll         string
+	query        *Query
+	rangeID      string
+	min, max     string
+	opts         []CallOption
+	charge       func(*Response)
+	items        []json.RawMessage
+	continuation string
+	done         bool
+}
+
+func (r *rangeQuery) next() (json.RawMessage, bool, error) {
+	for len(r.items) == 0 {
+		if r.done {
+			return nil, false, nil
+		}
+		if _, err := r.fetch(); err != nil {
+			return nil, false, err
+		}
+	}
+	item := r.items[0]
+	r.items = r.items[1:]
+	return item, true, nil
+}
+
+// fetch reads the next page of results
+func (r *rangeQuery) fetch() (*Response, error) {
+	var data struct {
+		Documents []json.RawMessage `json:"Documents"`
+	}
+	opts := append(r.opts[:len(r.opts):len(r.opts)], CrossPartition(), ChangeFeedPartitionRangeID(r.rangeID), Continuation(r.continuation))
+	res, err := r.db.client.QueryWithContext(r.ctx, r.coll+"docs/", r.query, &data, opts...)
+	if r.charge != nil {
+		r.charge(res)
+	}
+	if err != nil {
+		return res, err
+	}
+	r.items = data.Documents
+	r.continuation = res.Continuation()
+	r.done = r.continuation == ""
+	return res, nil
+}
+
+// concatStream returns the results of the ranges one range after the other
+type concatStream struct {
+	ranges []*rangeQuery
+}
+
+func (q *crossPartitionQuery) concat() resultStream {
+	return &concatStream{ranges: q.ranges}
+}
+
+func (s *concatStream) next() (json.RawMessage, bool, error) {
+	for len(s.ranges) > 0 {
+		item, ok, err := s.ranges[0].next()
+		if err != nil || ok {
+			return item, ok, err
+		}
+		s.ranges = s.ranges[1:]
+	}
+	return nil, false, nil
+}
+
+// orderByResult is a result of a rewritten ORDER BY query
+type orderByResult struct {
+	Rid          string                   `json:"_rid"`
+	OrderByItems []map[string]interface{} `json:"orderByItems"`
+	Payload      json.RawMessage          `json:"payload"`
+}
+
+// orderByStream merges the sorted results of the ranges
+type orderByStream struct {
+	ranges []*rangeQuery
+	order  []string
+	heads  orderByHeap
+	init   bool
+}
+
+type orderByHead struct {
+	result orderByResult
+	index  int
+}
+
+func (q *crossPartitionQuery) orderBy() resultStream {
+	s := &orderByStream{ranges: q.ranges, order: q.plan.QueryInfo.OrderBy}
+	s.heads.less = s.less
+	return s
+}
+
+func (s *orderByStream) next() (json.RawMessage, bool, error) {
+	if !s.init {
+		s.init = true
+		for i := range s.ranges {
+			if err := s.push(i); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	if s.heads.Len() == 0 {
+		return nil, false, nil
+	}
+	head := heap.Pop(&s.heads).(*orderByHead)
+	if err := s.push(head.index); err != nil {
+		return nil, false, err
+	}
+	return head.result.Payload, true, nil
+}
+
+// push reads the next result of the range into the heap
+func (s *orderByStream) push(i int) error {
+	item, ok, err := s.ranges[i].next()
+	if err != nil || !ok {
+		return err
+	}
+	head := &orderByHead{index: i}
+	if err := Serialization.Unmarshal(item, &head.result); err != nil {
+		return err
+	}
+	heap.Push(&s.heads, head)
+	return nil
+}
+
+func (s *orderByStream) less(a, b *orderByHead) bool {
+	for i, order := range s.order {
+		var x, y map[string]interface{}
+		if i < len(a.result.OrderByItems) {
+			x = a.result.OrderByItems[i]
+		}
+		if i < len(b.result.OrderByItems) {
+			y = b.result.OrderByItems[i]
+		}
+		vx, okx := x["item"]
+		vy, oky := y["item"]
+		cmp := compareValues(vx, okx, vy, oky)
+		if order == "Descending" {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+	// Ties keep the order of the ranges
+	return a.index < b.index
+}
+
+type orderByHeap struct {
+	heads []*orderByHead
+	less  func(a, b *orderByHead) bool
+}
+
+func (h orderByHeap) Len() int            { return len(h.heads) }
+func (h orderByHeap) Less(i, j int) bool  { return h.less(h.heads[i], h.heads[j]) }
+func (h orderByHeap) Swap(i, j int)       { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *orderByHeap) Push(x interface{}) { h.heads = append(h.heads, x.(*orderByHead)) }
+func (h *orderByHeap) Pop() interface{} {
+	n := len(h.heads)
+	x := h.heads[n-1]
+	h.heads = h.heads[:n-1]
+	return x
+}
+
+// takeStream skips and limits the results
+type takeStream struct {
+	source     resultStream
+	skip, take int
+}
+
+func (s *takeStream) next() (json.RawMessage, bool, error) {
+	for ; s.skip > 0; s.skip-- {
+		if _, ok, err := s.source.next(); err != nil || !ok {
+			return nil, false, err
+		}
+	}
+	if s.take == 0 {
+		return nil, false, nil
+	}
+	item, ok, err := s.source.next()
+	if ok && s.take > 0 {
+		s.take--
+	}
+	return item, ok, err
+}
+
+// distinctStream removes the duplicated results
+type distinctStream struct {
+	source resultStream
+	seen   map[string]bool
+}
+
+func (s *distinctStream) next() (json.RawMessage, bool, error) {
+	for {
+		item, ok, err := s.source.next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		key, err := canonical(item)
+		if err != nil {
+			return nil, false, err
+		}
+		if !s.seen[key] {
+			s.seen[key] = true
+			return item, true, nil
+		}
+	}
+}
+
+// canonical returns the json of item with sorted object keys and no spaces
+func canonical(item json.RawMessage) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(item, &v); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
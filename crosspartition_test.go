@@ -0,0 +1,294 @@
+package documentdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGateway serves a query plan, the partition key ranges and the pages of
+// results of each range
+type fakeGateway struct {
+	*MockServer
+	plan     string
+	ranges   []PartitionKeyRange
+	results  map[string][]string
+	pageSize int
+
+	// delay is the latency of the queries of the ranges
+	delay time.Duration
+
+	mu       sync.Mutex
+	queries  []string
+	inflight int
+	// maxInflight is the max number of queries served at the same time
+	maxInflight int
+}
+
+func newFakeGateway(plan string, results map[string][]string) *fakeGateway {
+	g := &fakeGateway{
+		plan: plan,
+		ranges: []PartitionKeyRange{
+			{PartitionKeyRangeID: "1", MinInclusive: "55", MaxInclusive: "AA"},
+			{PartitionKeyRangeID: "0", MinInclusive: "", MaxInclusive: "55"},
+			{PartitionKeyRangeID: "2", MinInclusive: "AA", MaxInclusive: "FF"},
+		},
+		results:  results,
+		pageSize: 2,
+	}
+	g.MockServer = HandlerFactory(g.serve)
+	return g
+}
+
+func (g *fakeGateway) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(HeaderRequestCharge, "1")
+	switch {
+	case r.Header.Get(HeaderIsQueryPlanRequest) != "":
+		fmt.Fprint(w, g.plan)
+	case strings.HasSuffix(r.URL.Path, "/pkranges/"):
+		serveRanges(w, g.ranges)
+	default:
+		var q Query
+		json.NewDecoder(r.Body).Decode(&q)
+		g.mu.Lock()
+		g.queries = append(g.queries, q.Query)
+		if g.inflight++; g.inflight > g.maxInflight {
+			g.maxInflight = g.inflight
+		}
+		g.mu.Unlock()
+		time.Sleep(g.delay)
+		defer func() {
+			g.mu.Lock()
+			g.inflight--
+			g.mu.Unlock()
+		}()
+
+		results := g.results[r.Header.Get(HeaderPartitionKeyRangeID)]
+		start, _ := strconv.Atoi(r.Header.Get(HeaderContinuation))
+		end := start + g.pageSize
+		if end < len(results) {
+			w.Header().Set(HeaderContinuation, strconv.Itoa(end))
+		} else {
+			end = len(results)
+		}
+		fmt.Fprintf(w, `{"Documents": [%s]}`, strings.Join(results[start:end], ","))
+	}
+}
+
+func orderByResults(values ...int) (results []string) {
+	for _, v := range values {
+		results = append(results, fmt.Sprintf(`{"_rid": "r%d", "orderByItems": [{"item": %d}], "payload": {"id": "%d", "n": %d}}`, v, v, v, v))
+	}
+	return
+}
+
+func TestCrossPartitionOrderBy(t *testing.T) {
+	assert := assert.New(t)
+	g := newFakeGateway(`{"queryInfo": {
+		"orderBy": ["Ascending"],
+		"orderByExpressions": ["c.n"],
+		"rewrittenQuery": "SELECT c._rid, [{\"item\": c.n}] AS orderByItems, c AS payload FROM c WHERE ({documentdb-formattableorderbyquery-filter}) ORDER BY c.n"
+	}, "queryRanges": [{"min": "", "max": "FF", "isMinInclusive": true, "isMaxInclusive": false}]}`, map[string][]string{
+		"0": orderByResults(1, 4, 7, 10),
+		"1": orderByResults(2, 5, 8),
+		"2": orderByResults(3, 6, 9, 11, 12),
+	})
+	defer g.Close()
+
+	var docs []struct {
+		N int `json:"n"`
+	}
+	res, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT * FROM c ORDER BY c.n"), &docs)
+	assert.Nil(err)
+	var ns []int
+	for _, doc := range docs {
+		ns = append(ns, doc.N)
+	}
+	assert.Equal([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, ns)
+	// plan + pkranges + 2 + 2 + 3 pages
+	assert.Equal(9.0, res.RequestCharge())
+	assert.Equal(`SELECT c._rid, [{"item": c.n}] AS orderByItems, c AS payload FROM c WHERE (true) ORDER BY c.n`, g.queries[0])
+}
+
+func TestCrossPartitionOrderByDescendingTop(t *testing.T) {
+	assert := assert.New(t)
+	g := newFakeGateway(`{"queryInfo": {"top": 4, "orderBy": ["Descending"], "rewrittenQuery": "SELECT TOP 4 c._rid, [{\"item\": c.n}] AS orderByItems, c AS payload FROM c ORDER BY c.n DESC"}}`, map[string][]string{
+		"0": orderByResults(10, 7, 4, 1),
+		"1": orderByResults(8, 5, 2),
+		"2": orderByResults(12, 11, 9, 6, 3),
+	})
+	defer g.Close()
+
+	var docs []struct {
+		ID string `json:"id"`
+	}
+	_, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT TOP 4 * FROM c ORDER BY c.n DESC"), &docs)
+	assert.Nil(err)
+	assert.Equal(4, len(docs))
+	assert.Equal("12", docs[0].ID)
+	assert.Equal("9", docs[3].ID)
+}
+
+func TestCrossPartitionOrderByMixedTypes(t *testing.T) {
+	assert := assert.New(t)
+	g := newFakeGateway(`{"queryInfo": {"orderBy": ["Ascending"]}}`, map[string][]string{
+		"0": {`{"orderByItems": [{"item": "a"}], "payload": "a"}`},
+		"1": {`{"orderByItems": [{}], "payload": "undefined"}`, `{"orderByItems": [{"item": 2}], "payload": 2}`},
+		"2": {`{"orderByItems": [{"item": null}], "payload": null}`, `{"orderByItems": [{"item": true}], "payload": true}`},
+	})
+	defer g.Close()
+
+	var docs []interface{}
+	_, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT VALUE c.v FROM c ORDER BY c.v"), &docs)
+	assert.Nil(err)
+	assert.Equal([]interface{}{"undefined", nil, true, 2.0, "a"}, docs)
+}
+
+func TestCrossPartitionOffsetLimitDistinct(t *testing.T) {
+	assert := assert.New(t)
+	g := newFakeGateway(`{"queryInfo": {"distinctType": "Unordered", "offset": 1, "limit": 3}}`, map[string][]string{
+		"0": {`"a"`, `"b"`, `"a"`},
+		"1": {`"c"`, `"b"`},
+		"2": {`"d"`, `"e"`, `"f"`},
+	})
+	defer g.Close()
+
+	var docs []string
+	_, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT DISTINCT VALUE c.v FROM c OFFSET 1 LIMIT 3"), &docs)
+	assert.Nil(err)
+	assert.Equal([]string{"b", "c", "d"}, docs)
+	// 2 pages of the first range, 1 of the others: the last range is not
+	// read further than needed
+	assert.Len(g.queries, 4)
+}
+
+func TestCrossPartitionQueryRanges(t *testing.T) {
+	assert := assert.New(t)
+	g := newFakeGateway(`{"queryInfo": {}, "queryRanges": [{"min": "60", "max": "60", "isMinInclusive": true, "isMaxInclusive": true}]}`, map[string][]string{
+		"0": {`1`},
+		"1": {`2`},
+		"2": {`3`},
+	})
+	defer g.Close()
+
+	var docs []int
+	_, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT VALUE c.n FROM c WHERE c.pk = 'x'"), &docs)
+	assert.Nil(err)
+	assert.Equal([]int{2}, docs)
+}
+
+func TestQueryRangeOverlaps(t *testing.T) {
+	r := PartitionKeyRange{MinInclusive: "40", MaxInclusive: "80"}
+	for _, c := range []struct {
+		q    queryRange
+		want bool
+	}{
+		{queryRange{Min: "", Max: "40"}, false},
+		{queryRange{Min: "", Max: "40", IsMaxInclusive: true}, true},
+		{queryRange{Min: "80", Max: "FF", IsMinInclusive: true}, false},
+		{queryRange{Min: "60", Max: "80", IsMaxInclusive: true}, true},
+		{queryRange{Min: "40", Max: "40", IsMinInclusive: true, IsMaxInclusive: true}, true},
+		{queryRange{Min: "40", Max: "40", IsMaxInclusive: true}, false},
+		{queryRange{Min: "60", Max: "60", IsMinInclusive: true}, false},
+		{queryRange{Min: "", Max: "FF", IsMinInclusive: true}, true},
+	} {
+		assert.Equal(t, c.want, c.q.overlaps(r), "%+v", c.q)
+	}
+}
+
+func TestCrossPartitionValueAggregates(t *testing.T) {
+	tests := []struct {
+		aggregate string
+		partials  []string
+		expected  string
+	}{
+		{"Count", []string{`[{"item": 2}]`, `[{"item": 0}]`, `[{"item": 5}]`}, `[7]`},
+		{"Sum", []string{`[{"item": 2.5}]`, `[{"item": 0}]`, `[{"item": 5}]`}, `[7.5]`},
+		{"Sum", []string{`[{"item": 2.5}]`, `[{}]`, `[{"item": 5}]`}, `[]`},
+		{"Average", []string{`[{"item": {"sum": 10, "count": 2}}]`, `[{"item": {"sum": 0, "count": 0}}]`, `[{"item": {"sum": 20, "count": 3}}]`}, `[6]`},
+		{"Average", []string{`[{"item": {"sum": 0, "count": 0}}]`, `[{"item": {"sum": 0, "count": 0}}]`, `[{"item": {"sum": 0, "count": 0}}]`}, `[]`},
+		{"Min", []string{`[{"item": 3}]`, `[{}]`, `[{"item": "a"}]`}, `[3]`},
+		{"Max", []string{`[{"item": 3}]`, `[{}]`, `[{"item": "a"}]`}, `["a"]`},
+		{"Max", []string{`[{"item": {"max": 3, "count": 1}}]`, `[{"item": {"count": 0}}]`, `[{"item": {"max": 9, "count": 4}}]`}, `[9]`},
+		{"Min", []string{`[{}]`, `[{}]`, `[{}]`}, `[]`},
+	}
+	for _, test := range tests {
+		g := newFakeGateway(fmt.Sprintf(`{"queryInfo": {"aggregates": [%q], "hasSelectValue": true}}`, test.aggregate), map[string][]string{
+			"0": {test.partials[0]},
+			"1": {test.partials[1]},
+			"2": {test.partials[2]},
+		})
+		var docs []interface{}
+		_, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT VALUE "+test.aggregate+"(c.n) FROM c"), &docs)
+		assert.Nil(t, err, test.aggregate)
+		b, _ := json.Marshal(docs)
+		if docs == nil {
+			b = []byte("[]")
+		}
+		assert.JSONEq(t, test.expected, string(b), test.aggregate+" %v", test.partials)
+		g.Close()
+	}
+}
+
+func TestCrossPartitionGroupBy(t *testing.T) {
+	assert := assert.New(t)
+	g := newFakeGateway(`{"queryInfo": {
+		"groupByExpressions": ["c.city"],
+		"groupByAliases": ["city", "n", "avg"],
+		"groupByAliasToAggregateType": {"city": null, "n": "Count", "avg": "Average"},
+		"aggregates": ["Count", "Average"],
+		"orderBy": ["Ascending"]
+	}}`, map[string][]string{
+		"0": {
+			`{"groupByItems": [{"item": "Paris"}], "payload": {"city": "Paris", "n": {"item": 2}, "avg": {"item": {"sum": 10, "count": 2}}}}`,
+			`{"groupByItems": [{"item": "Rome"}], "payload": {"city": "Rome", "n": {"item": 1}, "avg": {"item": {"sum": 3, "count": 1}}}}`,
+		},
+		"1": {
+			`{"groupByItems": [{"item": "Rome"}], "payload": {"avg": {"item": {"sum": 5, "count": 1}}, "n": {"item": 1}, "city": "Rome"}}`,
+		},
+		"2": {
+			`{"groupByItems": [{}], "payload": {"n": {"item": 3}, "avg": {"item": {"sum": 0, "count": 0}}}}`,
+		},
+	})
+	defer g.Close()
+
+	var docs []json.RawMessage
+	_, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT c.city, COUNT(1) AS n, AVG(c.age) AS avg FROM c GROUP BY c.city"), &docs)
+	assert.Nil(err)
+	if assert.Len(docs, 3) {
+		assert.Equal(`{"city":"Paris","n":2,"avg":5}`, string(docs[0]))
+		assert.Equal(`{"city":"Rome","n":2,"avg":4}`, string(docs[1]))
+		assert.Equal(`{"n":3}`, string(docs[2]))
+	}
+}
+
+func TestCrossPartitionGroupByValue(t *testing.T) {
+	assert := assert.New(t)
+	g := newFakeGateway(`{"queryInfo": {"groupByExpressions": ["c.city"], "hasSelectValue": true, "top": 2}}`, map[string][]string{
+		"0": {`{"groupByItems": [{"item": "Paris"}], "payload": "Paris"}`},
+		"1": {`{"groupByItems": [{"item": "Rome"}], "payload": "Rome"}`, `{"groupByItems": [{"item": "Paris"}], "payload": "Paris"}`},
+		"2": {`{"groupByItems": [{"item": "Oslo"}], "payload": "Oslo"}`},
+	})
+	defer g.Close()
+
+	var docs []string
+	_, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT TOP 2 VALUE c.city FROM c GROUP BY c.city"), &docs)
+	assert.Nil(err)
+	assert.Equal([]string{"Paris", "Rome"}, docs)
+}
+
+func TestCrossPartitionUnsupportedAggregate(t *testing.T) {
+	g := newFakeGateway(`{"queryInfo": {"aggregates": ["CountIf"], "hasSelectValue": true}}`, nil)
+	defer g.Close()
+
+	var docs []interface{}
+	_, err := g.DB().QueryDocumentsCrossPartition("dbs/db/colls/coll/", NewQuery("SELECT VALUE COUNTIF(c.n) FROM c"), &docs)
+	assert.EqualError(t, err, `documentdb: unsupported aggregate "CountIf"`)
+}
@@ -15,6 +15,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -27,7 +28,7 @@ var buffers = &sync.Pool{
 	},
 }
 
-var errAAD = errors.New("cannot perform CRUD operations on stored procedures or UDF's while authenticating with Azure AD")
+var errAAD = errors.New("cannot perform CRUD operations on stored procedures, triggers or UDF's while authenticating with Azure AD")
 
 // IdentificationHydrator defines interface for ID hydrators
 // that can prepopulate struct with default values
@@ -44,13 +45,22 @@ func DefaultIdentificationHydrator(config *Config, doc interface{}) {
 type Config struct {
 	MasterKey                  *Key
 	ServicePrincipal           ServicePrincipalProvider
+	ResourceTokens             map[string]string
 	Client                     http.Client
 	IdentificationHydrator     IdentificationHydrator
 	IdentificationPropertyName string
 	AppIdentifier              string
+	RetryPolicy                *RetryPolicy
+	SessionContainer           *SessionContainer
+	EnableEndpointDiscovery    bool
+	PreferredLocations         []string
+	EndpointRefreshInterval    time.Duration
 }
 
 func NewConfig(key *Key) *Config {
+	if key != nil {
+		key.decode()
+	}
 	return &Config{
 		MasterKey:                  key,
 		IdentificationHydrator:     DefaultIdentificationHydrator,
@@ -67,17 +77,59 @@ func NewConfigWithServicePrincipal(servicePrincipal ServicePrincipalProvider) *C
 	}
 }
 
+// NewConfigWithResourceTokens creates a new Config object that signs the requests with
+// resource tokens (the `_token` of a Permission), keyed by the link of the resource they
+// grant access to, e.g: {"dbs/db/colls/coll": token}.
+// Each request uses the token of its link or of the closest parent resource.
+func NewConfigWithResourceTokens(tokens map[string]string) *Config {
+	normalized := make(map[string]string, len(tokens))
+	for link, token := range tokens {
+		normalized[strings.Trim(link, "/")] = token
+	}
+	return &Config{
+		ResourceTokens:             normalized,
+		IdentificationHydrator:     DefaultIdentificationHydrator,
+		IdentificationPropertyName: "Id",
+	}
+}
+
 // WithClient stores given http client for later use by documentdb client.
 func (c *Config) WithClient(client http.Client) *Config {
 	c.Client = client
 	return c
 }
 
+// WithRetryPolicy sets the policy used to retry throttled requests.
+func (c *Config) WithRetryPolicy(policy RetryPolicy) *Config {
+	c.RetryPolicy = &policy
+	return c
+}
+
+// WithSessionContainer enables the automatic session token management,
+// the container is shared by all the clients created with this config.
+func (c *Config) WithSessionContainer(container *SessionContainer) *Config {
+	c.SessionContainer = container
+	return c
+}
+
+// WithEndpointDiscovery makes the client read the regions of the database
+// account, route the requests to the preferred locations (e.g: "West US")
+// and fail over to the other regions when one is not available.
+func (c *Config) WithEndpointDiscovery(preferredLocations ...string) *Config {
+	c.EnableEndpointDiscovery = true
+	c.PreferredLocations = preferredLocations
+	return c
+}
+
 func (c *Config) WithAppIdentifier(appIdentifier string) *Config {
 	c.AppIdentifier = appIdentifier
 	return c
 }
 
+// DocumentDB is the high level client.
+// Every operation has a `WithContext` variant that binds the request to a
+// context and, in addition to the decoded resource, returns the *Response
+// of the call (request charge, session token, etag, ...).
 type DocumentDB struct {
 	client Clienter
 	config *Config
@@ -90,6 +142,14 @@ func New(url string, config *Config) *DocumentDB {
 	}
 	client.Url = url
 	client.Config = config
+	if config.MasterKey != nil {
+		config.MasterKey.decode()
+	}
+	client.SessionContainer = config.SessionContainer
+	if config.EnableEndpointDiscovery {
+		// An invalid configuration fails every request
+		client.locations, client.locationsErr = newLocationCache(url, config.PreferredLocations, config.EndpointRefreshInterval)
+	}
 	client.UserAgent = strings.Join([]string{ClientName, "/", ReadClientVersion(), " ", config.AppIdentifier}, "")
 	return &DocumentDB{client: client, config: config}
 }
@@ -97,98 +157,162 @@ func New(url string, config *Config) *DocumentDB {
 // TODO: Add `requestOptions` arguments
 // Read database by self link
 func (c *DocumentDB) ReadDatabase(link string, opts ...CallOption) (db *Database, err error) {
-	_, err = c.client.Read(link, &db, opts...)
+	db, _, err = c.ReadDatabaseWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadDatabaseWithContext reads database by self link, the request is bound to ctx
+func (c *DocumentDB) ReadDatabaseWithContext(ctx context.Context, link string, opts ...CallOption) (db *Database, res *Response, err error) {
+	res, err = c.client.ReadWithContext(ctx, link, &db, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Read collection by self link
 func (c *DocumentDB) ReadCollection(link string, opts ...CallOption) (coll *Collection, err error) {
-	_, err = c.client.Read(link, &coll, opts...)
+	coll, _, err = c.ReadCollectionWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadCollectionWithContext reads collection by self link, the request is bound to ctx
+func (c *DocumentDB) ReadCollectionWithContext(ctx context.Context, link string, opts ...CallOption) (coll *Collection, res *Response, err error) {
+	res, err = c.client.ReadWithContext(ctx, link, &coll, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Read document by self link
 func (c *DocumentDB) ReadDocument(link string, doc interface{}, opts ...CallOption) (err error) {
-	_, err = c.client.Read(link, &doc, opts...)
+	_, err = c.ReadDocumentWithContext(context.Background(), link, doc, opts...)
 	return
 }
 
+// ReadDocumentWithContext reads document by self link, the request is bound to ctx
+func (c *DocumentDB) ReadDocumentWithContext(ctx context.Context, link string, doc interface{}, opts ...CallOption) (*Response, error) {
+	return c.client.ReadWithContext(ctx, link, &doc, opts...)
+}
+
 // Read sporc by self link
 func (c *DocumentDB) ReadStoredProcedure(link string, opts ...CallOption) (sproc *Sproc, err error) {
+	sproc, _, err = c.ReadStoredProcedureWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadStoredProcedureWithContext reads sporc by self link, the request is bound to ctx
+func (c *DocumentDB) ReadStoredProcedureWithContext(ctx context.Context, link string, opts ...CallOption) (sproc *Sproc, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
-	_, err = c.client.Read(link, &sproc, opts...)
+	res, err = c.client.ReadWithContext(ctx, link, &sproc, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Read udf by self link
 func (c *DocumentDB) ReadUserDefinedFunction(link string, opts ...CallOption) (udf *UDF, err error) {
+	udf, _, err = c.ReadUserDefinedFunctionWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadUserDefinedFunctionWithContext reads udf by self link, the request is bound to ctx
+func (c *DocumentDB) ReadUserDefinedFunctionWithContext(ctx context.Context, link string, opts ...CallOption) (udf *UDF, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
-	_, err = c.client.Read(link, &udf, opts...)
+	res, err = c.client.ReadWithContext(ctx, link, &udf, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Read all databases
 func (c *DocumentDB) ReadDatabases(opts ...CallOption) (dbs []Database, err error) {
-	return c.QueryDatabases(nil, opts...)
+	dbs, _, err = c.ReadDatabasesWithContext(context.Background(), opts...)
+	return
+}
+
+// ReadDatabasesWithContext reads all databases, the request is bound to ctx
+func (c *DocumentDB) ReadDatabasesWithContext(ctx context.Context, opts ...CallOption) (dbs []Database, res *Response, err error) {
+	return c.QueryDatabasesWithContext(ctx, nil, opts...)
 }
 
 // Read all collections by db selflink
 func (c *DocumentDB) ReadCollections(db string, opts ...CallOption) (colls []Collection, err error) {
-	return c.QueryCollections(db, nil, opts...)
+	colls, _, err = c.ReadCollectionsWithContext(context.Background(), db, opts...)
+	return
+}
+
+// ReadCollectionsWithContext reads all collections by db selflink, the request is bound to ctx
+func (c *DocumentDB) ReadCollectionsWithContext(ctx context.Context, db string, opts ...CallOption) (colls []Collection, res *Response, err error) {
+	return c.QueryCollectionsWithContext(ctx, db, nil, opts...)
 }
 
 // Read all sprocs by collection self link
 func (c *DocumentDB) ReadStoredProcedures(coll string, opts ...CallOption) (sprocs []Sproc, err error) {
+	sprocs, _, err = c.ReadStoredProceduresWithContext(context.Background(), coll, opts...)
+	return
+}
+
+// ReadStoredProceduresWithContext reads all sprocs by collection self link, the request is bound to ctx
+func (c *DocumentDB) ReadStoredProceduresWithContext(ctx context.Context, coll string, opts ...CallOption) (sprocs []Sproc, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
-	return c.QueryStoredProcedures(coll, nil, opts...)
+	return c.QueryStoredProceduresWithContext(ctx, coll, nil, opts...)
 }
 
 // Read pall udfs by collection self link
 func (c *DocumentDB) ReadUserDefinedFunctions(coll string, opts ...CallOption) (udfs []UDF, err error) {
+	udfs, _, err = c.ReadUserDefinedFunctionsWithContext(context.Background(), coll, opts...)
+	return
+}
+
+// ReadUserDefinedFunctionsWithContext reads all udfs by collection self link, the request is bound to ctx
+func (c *DocumentDB) ReadUserDefinedFunctionsWithContext(ctx context.Context, coll string, opts ...CallOption) (udfs []UDF, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
-	return c.QueryUserDefinedFunctions(coll, nil, opts...)
+	return c.QueryUserDefinedFunctionsWithContext(ctx, coll, nil, opts...)
 }
 
 // Read all collection documents by self link
 // TODO: use iterator for heavy transactions
 func (c *DocumentDB) ReadDocuments(coll string, docs interface{}, opts ...CallOption) (r *Response, err error) {
-	return c.QueryDocuments(coll, nil, docs, opts...)
+	return c.ReadDocumentsWithContext(context.Background(), coll, docs, opts...)
+}
+
+// ReadDocumentsWithContext reads all collection documents by self link, the request is bound to ctx
+func (c *DocumentDB) ReadDocumentsWithContext(ctx context.Context, coll string, docs interface{}, opts ...CallOption) (r *Response, err error) {
+	return c.QueryDocumentsWithContext(ctx, coll, nil, docs, opts...)
 }
 
 // Read all databases that satisfy a query
 func (c *DocumentDB) QueryDatabases(query *Query, opts ...CallOption) (dbs Databases, err error) {
+	dbs, _, err = c.QueryDatabasesWithContext(context.Background(), query, opts...)
+	return
+}
+
+// QueryDatabasesWithContext reads all databases that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryDatabasesWithContext(ctx context.Context, query *Query, opts ...CallOption) (dbs Databases, res *Response, err error) {
 	data := struct {
 		Databases Databases `json:"Databases,omitempty"`
 		Count     int       `json:"_count,omitempty"`
 	}{}
 	if query != nil {
-		_, err = c.client.Query("dbs", query, &data, opts...)
+		res, err = c.client.QueryWithContext(ctx, "dbs", query, &data, opts...)
 	} else {
-		_, err = c.client.Read("dbs", &data, opts...)
+		res, err = c.client.ReadWithContext(ctx, "dbs", &data, opts...)
 	}
 	if dbs = data.Databases; err != nil {
 		dbs = nil
@@ -198,14 +322,20 @@ func (c *DocumentDB) QueryDatabases(query *Query, opts ...CallOption) (dbs Datab
 
 // Read all db-collection that satisfy a query
 func (c *DocumentDB) QueryCollections(db string, query *Query, opts ...CallOption) (colls []Collection, err error) {
+	colls, _, err = c.QueryCollectionsWithContext(context.Background(), db, query, opts...)
+	return
+}
+
+// QueryCollectionsWithContext reads all db-collection that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryCollectionsWithContext(ctx context.Context, db string, query *Query, opts ...CallOption) (colls []Collection, res *Response, err error) {
 	data := struct {
 		Collections []Collection `json:"DocumentCollections,omitempty"`
 		Count       int          `json:"_count,omitempty"`
 	}{}
 	if query != nil {
-		_, err = c.client.Query(db+"colls/", query, &data, opts...)
+		res, err = c.client.QueryWithContext(ctx, db+"colls/", query, &data, opts...)
 	} else {
-		_, err = c.client.Read(db+"colls/", &data, opts...)
+		res, err = c.client.ReadWithContext(ctx, db+"colls/", &data, opts...)
 	}
 	if colls = data.Collections; err != nil {
 		colls = nil
@@ -215,8 +345,14 @@ func (c *DocumentDB) QueryCollections(db string, query *Query, opts ...CallOptio
 
 // Read all collection `sprocs` that satisfy a query
 func (c *DocumentDB) QueryStoredProcedures(coll string, query *Query, opts ...CallOption) (sprocs []Sproc, err error) {
+	sprocs, _, err = c.QueryStoredProceduresWithContext(context.Background(), coll, query, opts...)
+	return
+}
+
+// QueryStoredProceduresWithContext reads all collection `sprocs` that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryStoredProceduresWithContext(ctx context.Context, coll string, query *Query, opts ...CallOption) (sprocs []Sproc, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
 	data := struct {
@@ -224,9 +360,9 @@ func (c *DocumentDB) QueryStoredProcedures(coll string, query *Query, opts ...Ca
 		Count  int     `json:"_count,omitempty"`
 	}{}
 	if query != nil {
-		_, err = c.client.Query(coll+"sprocs/", query, &data, opts...)
+		res, err = c.client.QueryWithContext(ctx, coll+"sprocs/", query, &data, opts...)
 	} else {
-		_, err = c.client.Read(coll+"sprocs/", &data, opts...)
+		res, err = c.client.ReadWithContext(ctx, coll+"sprocs/", &data, opts...)
 	}
 	if sprocs = data.Sprocs; err != nil {
 		sprocs = nil
@@ -236,8 +372,14 @@ func (c *DocumentDB) QueryStoredProcedures(coll string, query *Query, opts ...Ca
 
 // Read all collection `udfs` that satisfy a query
 func (c *DocumentDB) QueryUserDefinedFunctions(coll string, query *Query, opts ...CallOption) (udfs []UDF, err error) {
+	udfs, _, err = c.QueryUserDefinedFunctionsWithContext(context.Background(), coll, query, opts...)
+	return
+}
+
+// QueryUserDefinedFunctionsWithContext reads all collection `udfs` that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryUserDefinedFunctionsWithContext(ctx context.Context, coll string, query *Query, opts ...CallOption) (udfs []UDF, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
 	data := struct {
@@ -245,9 +387,9 @@ func (c *DocumentDB) QueryUserDefinedFunctions(coll string, query *Query, opts .
 		Count int   `json:"_count,omitempty"`
 	}{}
 	if query != nil {
-		_, err = c.client.Query(coll+"udfs/", query, &data, opts...)
+		res, err = c.client.QueryWithContext(ctx, coll+"udfs/", query, &data, opts...)
 	} else {
-		_, err = c.client.Read(coll+"udfs/", &data, opts...)
+		res, err = c.client.ReadWithContext(ctx, coll+"udfs/", &data, opts...)
 	}
 	if udfs = data.Udfs; err != nil {
 		udfs = nil
@@ -257,25 +399,36 @@ func (c *DocumentDB) QueryUserDefinedFunctions(coll string, query *Query, opts .
 
 // Read all documents in a collection that satisfy a query
 func (c *DocumentDB) QueryDocuments(coll string, query *Query, docs interface{}, opts ...CallOption) (response *Response, err error) {
+	return c.QueryDocumentsWithContext(context.Background(), coll, query, docs, opts...)
+}
+
+// QueryDocumentsWithContext reads all documents in a collection that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryDocumentsWithContext(ctx context.Context, coll string, query *Query, docs interface{}, opts ...CallOption) (response *Response, err error) {
 	data := struct {
 		Documents interface{} `json:"Documents,omitempty"`
 		Count     int         `json:"_count,omitempty"`
 	}{Documents: docs}
 	if query != nil {
-		response, err = c.client.Query(coll+"docs/", query, &data, opts...)
+		response, err = c.client.QueryWithContext(ctx, coll+"docs/", query, &data, opts...)
 	} else {
-		response, err = c.client.Read(coll+"docs/", &data, opts...)
+		response, err = c.client.ReadWithContext(ctx, coll+"docs/", &data, opts...)
 	}
 	return
 }
 
 // Read collection's partition ranges
 func (c *DocumentDB) QueryPartitionKeyRanges(coll string, query *Query, opts ...CallOption) (ranges []PartitionKeyRange, err error) {
+	ranges, _, err = c.QueryPartitionKeyRangesWithContext(context.Background(), coll, query, opts...)
+	return
+}
+
+// QueryPartitionKeyRangesWithContext reads collection's partition ranges, the request is bound to ctx
+func (c *DocumentDB) QueryPartitionKeyRangesWithContext(ctx context.Context, coll string, query *Query, opts ...CallOption) (ranges []PartitionKeyRange, res *Response, err error) {
 	data := queryPartitionKeyRangesRequest{}
 	if query != nil {
-		_, err = c.client.Query(coll+"pkranges/", query, &data, opts...)
+		res, err = c.client.QueryWithContext(ctx, coll+"pkranges/", query, &data, opts...)
 	} else {
-		_, err = c.client.Read(coll+"pkranges/", &data, opts...)
+		res, err = c.client.ReadWithContext(ctx, coll+"pkranges/", &data, opts...)
 	}
 	if ranges = data.Ranges; err != nil {
 		ranges = nil
@@ -285,147 +438,295 @@ func (c *DocumentDB) QueryPartitionKeyRanges(coll string, query *Query, opts ...
 
 // Create database
 func (c *DocumentDB) CreateDatabase(body interface{}, opts ...CallOption) (db *Database, err error) {
-	_, err = c.client.Create("dbs", body, &db, opts...)
+	db, _, err = c.CreateDatabaseWithContext(context.Background(), body, opts...)
+	return
+}
+
+// CreateDatabaseWithContext creates database, the request is bound to ctx
+func (c *DocumentDB) CreateDatabaseWithContext(ctx context.Context, body interface{}, opts ...CallOption) (db *Database, res *Response, err error) {
+	res, err = c.client.CreateWithContext(ctx, "dbs", body, &db, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Create collection
 func (c *DocumentDB) CreateCollection(db string, body interface{}, opts ...CallOption) (coll *Collection, err error) {
-	_, err = c.client.Create(db+"colls/", body, &coll, opts...)
+	coll, _, err = c.CreateCollectionWithContext(context.Background(), db, body, opts...)
+	return
+}
+
+// CreateCollectionWithContext creates collection, the request is bound to ctx
+func (c *DocumentDB) CreateCollectionWithContext(ctx context.Context, db string, body interface{}, opts ...CallOption) (coll *Collection, res *Response, err error) {
+	res, err = c.client.CreateWithContext(ctx, db+"colls/", body, &coll, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Create stored procedure
 func (c *DocumentDB) CreateStoredProcedure(coll string, body interface{}, opts ...CallOption) (sproc *Sproc, err error) {
+	sproc, _, err = c.CreateStoredProcedureWithContext(context.Background(), coll, body, opts...)
+	return
+}
+
+// CreateStoredProcedureWithContext creates stored procedure, the request is bound to ctx
+func (c *DocumentDB) CreateStoredProcedureWithContext(ctx context.Context, coll string, body interface{}, opts ...CallOption) (sproc *Sproc, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
-	_, err = c.client.Create(coll+"sprocs/", body, &sproc, opts...)
+	res, err = c.client.CreateWithContext(ctx, coll+"sprocs/", body, &sproc, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Create user defined function
 func (c *DocumentDB) CreateUserDefinedFunction(coll string, body interface{}, opts ...CallOption) (udf *UDF, err error) {
+	udf, _, err = c.CreateUserDefinedFunctionWithContext(context.Background(), coll, body, opts...)
+	return
+}
+
+// CreateUserDefinedFunctionWithContext creates user defined function, the request is bound to ctx
+func (c *DocumentDB) CreateUserDefinedFunctionWithContext(ctx context.Context, coll string, body interface{}, opts ...CallOption) (udf *UDF, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
-	_, err = c.client.Create(coll+"udfs/", body, &udf, opts...)
+	res, err = c.client.CreateWithContext(ctx, coll+"udfs/", body, &udf, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Create document
 func (c *DocumentDB) CreateDocument(coll string, doc interface{}, opts ...CallOption) (*Response, error) {
+	return c.CreateDocumentWithContext(context.Background(), coll, doc, opts...)
+}
+
+// CreateDocumentWithContext creates document, the request is bound to ctx
+func (c *DocumentDB) CreateDocumentWithContext(ctx context.Context, coll string, doc interface{}, opts ...CallOption) (*Response, error) {
 	if c.config != nil && c.config.IdentificationHydrator != nil {
 		c.config.IdentificationHydrator(c.config, doc)
 	}
-	return c.client.Create(coll+"docs/", doc, &doc, opts...)
+	return c.client.CreateWithContext(ctx, coll+"docs/", doc, &doc, opts...)
 }
 
 // Upsert document
 func (c *DocumentDB) UpsertDocument(coll string, doc interface{}, opts ...CallOption) (*Response, error) {
+	return c.UpsertDocumentWithContext(context.Background(), coll, doc, opts...)
+}
+
+// UpsertDocumentWithContext upserts document, the request is bound to ctx
+func (c *DocumentDB) UpsertDocumentWithContext(ctx context.Context, coll string, doc interface{}, opts ...CallOption) (*Response, error) {
 	if c.config != nil && c.config.IdentificationHydrator != nil {
 		c.config.IdentificationHydrator(c.config, doc)
 	}
-	return c.client.Upsert(coll+"docs/", doc, &doc, opts...)
+	return c.client.UpsertWithContext(ctx, coll+"docs/", doc, &doc, opts...)
 }
 
 // TODO: DRY, but the sdk want that[mm.. maybe just client.Delete(self_link)]
 // Delete database
 func (c *DocumentDB) DeleteDatabase(link string, opts ...CallOption) (*Response, error) {
-	return c.client.Delete(link, opts...)
+	return c.DeleteDatabaseWithContext(context.Background(), link, opts...)
+}
+
+// DeleteDatabaseWithContext deletes database, the request is bound to ctx
+func (c *DocumentDB) DeleteDatabaseWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.client.DeleteWithContext(ctx, link, opts...)
 }
 
 // Delete collection
 func (c *DocumentDB) DeleteCollection(link string, opts ...CallOption) (*Response, error) {
-	return c.client.Delete(link, opts...)
+	return c.DeleteCollectionWithContext(context.Background(), link, opts...)
+}
+
+// DeleteCollectionWithContext deletes collection, the request is bound to ctx
+func (c *DocumentDB) DeleteCollectionWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.client.DeleteWithContext(ctx, link, opts...)
 }
 
 // Delete document
 func (c *DocumentDB) DeleteDocument(link string, opts ...CallOption) (*Response, error) {
-	return c.client.Delete(link, opts...)
+	return c.DeleteDocumentWithContext(context.Background(), link, opts...)
+}
+
+// DeleteDocumentWithContext deletes document, the request is bound to ctx
+func (c *DocumentDB) DeleteDocumentWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.client.DeleteWithContext(ctx, link, opts...)
 }
 
 // Delete stored procedure
 func (c *DocumentDB) DeleteStoredProcedure(link string, opts ...CallOption) (*Response, error) {
+	return c.DeleteStoredProcedureWithContext(context.Background(), link, opts...)
+}
+
+// DeleteStoredProcedureWithContext deletes stored procedure, the request is bound to ctx
+func (c *DocumentDB) DeleteStoredProcedureWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
 	if c.usesAAD() {
 		return nil, errAAD
 	}
 
-	return c.client.Delete(link, opts...)
+	return c.client.DeleteWithContext(ctx, link, opts...)
 }
 
 // Delete user defined function
 func (c *DocumentDB) DeleteUserDefinedFunction(link string, opts ...CallOption) (*Response, error) {
+	return c.DeleteUserDefinedFunctionWithContext(context.Background(), link, opts...)
+}
+
+// DeleteUserDefinedFunctionWithContext deletes user defined function, the request is bound to ctx
+func (c *DocumentDB) DeleteUserDefinedFunctionWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
 	if c.usesAAD() {
 		return nil, errAAD
 	}
 
-	return c.client.Delete(link, opts...)
+	return c.client.DeleteWithContext(ctx, link, opts...)
 }
 
 // Replace database
 func (c *DocumentDB) ReplaceDatabase(link string, body interface{}, opts ...CallOption) (db *Database, err error) {
-	_, err = c.client.Replace(link, body, &db)
+	db, _, err = c.ReplaceDatabaseWithContext(context.Background(), link, body, opts...)
+	return
+}
+
+// ReplaceDatabaseWithContext replaces database, the request is bound to ctx
+func (c *DocumentDB) ReplaceDatabaseWithContext(ctx context.Context, link string, body interface{}, opts ...CallOption) (db *Database, res *Response, err error) {
+	res, err = c.client.ReplaceWithContext(ctx, link, body, &db, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Replace collection, e.g: change its indexing policy or default TTL.
+// The partition key and the unique keys can't be changed
+func (c *DocumentDB) ReplaceCollection(link string, body interface{}, opts ...CallOption) (coll *Collection, err error) {
+	coll, _, err = c.ReplaceCollectionWithContext(context.Background(), link, body, opts...)
+	return
+}
+
+// ReplaceCollectionWithContext replaces collection, the request is bound to ctx
+func (c *DocumentDB) ReplaceCollectionWithContext(ctx context.Context, link string, body interface{}, opts ...CallOption) (coll *Collection, res *Response, err error) {
+	res, err = c.client.ReplaceWithContext(ctx, link, body, &coll, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
+// DefaultIndexTransformationInterval is the default polling interval of WaitForIndexTransformation
+const DefaultIndexTransformationInterval = time.Second
+
+// WaitForIndexTransformation polls the collection every interval (DefaultIndexTransformationInterval
+// if not positive) until its index transformation (e.g: after replacing its indexing policy)
+// completes or ctx is done. progress, if not nil, is called with the percentage reported by each poll
+func (c *DocumentDB) WaitForIndexTransformation(ctx context.Context, link string, interval time.Duration, progress func(percent int), opts ...CallOption) error {
+	if interval <= 0 {
+		interval = DefaultIndexTransformationInterval
+	}
+	opts = append(opts[:len(opts):len(opts)], PopulateQuotaInfo())
+	for {
+		_, res, err := c.ReadCollectionWithContext(ctx, link, opts...)
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			return errors.New("documentdb: no response to read the index transformation progress")
+		}
+		// The header is missing when there's nothing to transform
+		percent, ok := res.IndexTransformationProgress()
+		if !ok {
+			percent = 100
+		}
+		if progress != nil {
+			progress(percent)
+		}
+		if percent >= 100 {
+			return nil
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
 // Replace document
 func (c *DocumentDB) ReplaceDocument(link string, doc interface{}, opts ...CallOption) (*Response, error) {
-	return c.client.Replace(link, doc, &doc, opts...)
+	return c.ReplaceDocumentWithContext(context.Background(), link, doc, opts...)
+}
+
+// ReplaceDocumentWithContext replaces document, the request is bound to ctx
+func (c *DocumentDB) ReplaceDocumentWithContext(ctx context.Context, link string, doc interface{}, opts ...CallOption) (*Response, error) {
+	return c.client.ReplaceWithContext(ctx, link, doc, &doc, opts...)
 }
 
 // Replace stored procedure
 func (c *DocumentDB) ReplaceStoredProcedure(link string, body interface{}, opts ...CallOption) (sproc *Sproc, err error) {
+	sproc, _, err = c.ReplaceStoredProcedureWithContext(context.Background(), link, body, opts...)
+	return
+}
+
+// ReplaceStoredProcedureWithContext replaces stored procedure, the request is bound to ctx
+func (c *DocumentDB) ReplaceStoredProcedureWithContext(ctx context.Context, link string, body interface{}, opts ...CallOption) (sproc *Sproc, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
-	_, err = c.client.Replace(link, body, &sproc, opts...)
+	res, err = c.client.ReplaceWithContext(ctx, link, body, &sproc, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Replace stored procedure
 func (c *DocumentDB) ReplaceUserDefinedFunction(link string, body interface{}, opts ...CallOption) (udf *UDF, err error) {
+	udf, _, err = c.ReplaceUserDefinedFunctionWithContext(context.Background(), link, body, opts...)
+	return
+}
+
+// ReplaceUserDefinedFunctionWithContext replaces user defined function, the request is bound to ctx
+func (c *DocumentDB) ReplaceUserDefinedFunctionWithContext(ctx context.Context, link string, body interface{}, opts ...CallOption) (udf *UDF, res *Response, err error) {
 	if c.usesAAD() {
-		return nil, errAAD
+		return nil, nil, errAAD
 	}
 
-	_, err = c.client.Replace(link, body, &udf, opts...)
+	res, err = c.client.ReplaceWithContext(ctx, link, body, &udf, opts...)
 	if err != nil {
-		return nil, err
+		return nil, res, err
 	}
 	return
 }
 
 // Execute stored procedure
 func (c *DocumentDB) ExecuteStoredProcedure(link string, params, body interface{}, opts ...CallOption) (err error) {
-	_, err = c.client.Execute(link, params, &body, opts...)
+	_, err = c.ExecuteStoredProcedureWithContext(context.Background(), link, params, body, opts...)
 	return
 }
 
+// ExecuteStoredProcedureWithContext executes stored procedure, the request is bound to ctx
+func (c *DocumentDB) ExecuteStoredProcedureWithContext(ctx context.Context, link string, params, body interface{}, opts ...CallOption) (*Response, error) {
+	return c.client.ExecuteWithContext(ctx, link, params, &body, opts...)
+}
+
+// SessionContainer returns the container of the session tokens, or nil if
+// the automatic session token management is disabled
+func (c *DocumentDB) SessionContainer() *SessionContainer {
+	if c.config == nil {
+		return nil
+	}
+	return c.config.SessionContainer
+}
+
 // usesAAD returns true if the client is authenticated with Azure AD
 func (c *DocumentDB) usesAAD() bool {
-	return c.config.ServicePrincipal != nil
+	return c.config != nil && c.config.ServicePrincipal != nil
 }
 
 // ServicePrincipalProvider is an interface for an object that provides an Azure service principal
@@ -1,8 +1,15 @@
 package documentdb
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -51,6 +58,44 @@ func (c *ClientStub) Execute(link string, body, ret interface{}, opts ...CallOpt
 	return nil, nil
 }
 
+func (c *ClientStub) ReadWithContext(ctx context.Context, link string, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.Read(link, ret, opts...)
+}
+
+func (c *ClientStub) QueryWithContext(ctx context.Context, link string, query *Query, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.Query(link, query, ret, opts...)
+}
+
+func (c *ClientStub) CreateWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.Create(link, body, ret, opts...)
+}
+
+func (c *ClientStub) UpsertWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.Upsert(link, body, ret, opts...)
+}
+
+func (c *ClientStub) DeleteWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.Delete(link, opts...)
+}
+
+func (c *ClientStub) ReplaceWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.Replace(link, body, ret, opts...)
+}
+
+func (c *ClientStub) ExecuteWithContext(ctx context.Context, link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
+	return c.Execute(link, body, ret, opts...)
+}
+
+func (c *ClientStub) Stream(link string, opts ...CallOption) (io.ReadCloser, *Response, error) {
+	args := c.Called(link)
+	body, _ := args.Get(0).(io.ReadCloser)
+	return body, nil, args.Error(1)
+}
+
+func (c *ClientStub) StreamWithContext(ctx context.Context, link string, opts ...CallOption) (io.ReadCloser, *Response, error) {
+	return c.Stream(link, opts...)
+}
+
 var defaultConfig = &Config{
 	IdentificationHydrator:     DefaultIdentificationHydrator,
 	IdentificationPropertyName: "Id",
@@ -79,6 +124,17 @@ func TestReadDatabase(t *testing.T) {
 	client.AssertCalled(t, "Read", "self_link", mock.Anything, mock.Anything)
 }
 
+func TestReadDatabaseWithContext(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	expected := &Response{StatusCode: 200}
+	client.On("Read", "self_link", mock.Anything, mock.Anything).Return(expected, nil)
+	_, res, err := c.ReadDatabaseWithContext(context.Background(), "self_link")
+	client.AssertCalled(t, "Read", "self_link", mock.Anything, mock.Anything)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, res, "Should return the client response")
+}
+
 func TestReadCollection(t *testing.T) {
 	client := &ClientStub{}
 	c := &DocumentDB{client, nil}
@@ -307,6 +363,59 @@ func TestReplaceDatabase(t *testing.T) {
 	client.AssertCalled(t, "Replace", "db_link", "{}")
 }
 
+func TestReplaceCollection(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Replace", "coll_link", "{}").Return(nil)
+	c.ReplaceCollection("coll_link", "{}")
+	client.AssertCalled(t, "Replace", "coll_link", "{}")
+}
+
+func TestWaitForIndexTransformation(t *testing.T) {
+	assert := assert.New(t)
+	var polls int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("true", r.Header.Get(HeaderPopulateQuotaInfo))
+		polls++
+		w.Header().Set(HeaderIndexTransformation, strconv.Itoa(polls*50))
+		fmt.Fprintln(w, `{"id": "coll"}`)
+	}))
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+	c := &DocumentDB{client, client.Config}
+
+	var reported []int
+	err := c.WaitForIndexTransformation(context.Background(), "dbs/db/colls/coll", time.Millisecond, func(percent int) {
+		reported = append(reported, percent)
+	})
+	assert.Nil(err)
+	assert.Equal([]int{50, 100}, reported)
+
+	// Stop when the context is done
+	polls = -100
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	err = c.WaitForIndexTransformation(ctx, "dbs/db/colls/coll", time.Millisecond, nil)
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+
+	// The progress is read from the response of the client
+	stub := &ClientStub{}
+	header := http.Header{}
+	header.Set(HeaderIndexTransformation, "100")
+	stub.On("Read", "dbs/db/colls/coll", mock.Anything, mock.Anything).Return(&Response{Header: header}, nil).Once()
+	reported = nil
+	err = (&DocumentDB{stub, nil}).WaitForIndexTransformation(context.Background(), "dbs/db/colls/coll", 0, func(percent int) {
+		reported = append(reported, percent)
+	})
+	assert.Nil(err)
+	assert.Equal([]int{100}, reported)
+
+	// A client without response fails
+	stub.On("Read", "dbs/db/colls/coll", mock.Anything, mock.Anything).Return(nil, nil)
+	err = (&DocumentDB{stub, nil}).WaitForIndexTransformation(context.Background(), "dbs/db/colls/coll", 0, nil)
+	assert.EqualError(err, "documentdb: no response to read the index transformation progress")
+}
+
 func TestReplaceDocument(t *testing.T) {
 	client := &ClientStub{}
 	c := &DocumentDB{client, nil}
@@ -355,4 +464,9 @@ func TestQueryPartitionKeyRanges(t *testing.T) {
 	client.AssertCalled(t, "Read", "coll_link/pkranges/", mock.Anything, mock.Anything)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedRanges, ranges, "Ranges are different")
+
+	ranges, res, err := c.QueryPartitionKeyRangesWithContext(context.Background(), "coll_link/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRanges, ranges, "Ranges are different")
+	assert.Equal(t, &Response{}, res, "Should return the client response")
 }
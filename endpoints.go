@@ -0,0 +1,287 @@
+package documentdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultEndpointRefreshInterval is how often the regions of the account
+	// are read again when endpoint discovery is enabled
+	DefaultEndpointRefreshInterval = 5 * time.Minute
+
+	// unavailableEndpointTTL is how long an endpoint that failed is moved
+	// to the end of the list
+	unavailableEndpointTTL = 5 * time.Minute
+)
+
+// locationCache holds the regional endpoints of the database account, ordered
+// by the preferred locations, and the endpoints that are currently unavailable
+type locationCache struct {
+	mu          sync.Mutex
+	fallback    *url.URL
+	preferred   []string
+	interval    time.Duration
+	writes      []*url.URL
+	reads       []*url.URL
+	unavailable map[string]time.Time
+	refreshed   time.Time
+	refreshing  bool
+}
+
+func newLocationCache(endpoint string, preferred []string, interval time.Duration) (*locationCache, error) {
+	fallback, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if fallback.Host == "" {
+		return nil, fmt.Errorf("documentdb: invalid endpoint %q", endpoint)
+	}
+	for _, name := range preferred {
+		if strings.TrimSpace(name) == "" {
+			return nil, errors.New("documentdb: empty preferred location")
+		}
+	}
+	if interval <= 0 {
+		interval = DefaultEndpointRefreshInterval
+	}
+	return &locationCache{
+		fallback:    fallback,
+		preferred:   preferred,
+		interval:    interval,
+		unavailable: make(map[string]time.Time),
+	}, nil
+}
+
+// endpoints returns the endpoints for reads or writes, by order of preference.
+// Unavailable endpoints are moved to the end of the list
+func (l *locationCache) endpoints(read bool) []*url.URL {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	all := l.writes
+	if read {
+		all = l.reads
+	}
+	if len(all) == 0 {
+		return []*url.URL{l.fallback}
+	}
+	available := make([]*url.URL, 0, len(all))
+	var unavailable []*url.URL
+	for _, u := range all {
+		if at, ok := l.unavailable[u.Host]; ok && time.Since(at) < unavailableEndpointTTL {
+			unavailable = append(unavailable, u)
+		} else {
+			available = append(available, u)
+		}
+	}
+	return append(available, unavailable...)
+}
+
+// markUnavailable moves endpoint to the end of the lists for a while
+func (l *locationCache) markUnavailable(endpoint *url.URL) {
+	l.mu.Lock()
+	l.unavailable[endpoint.Host] = time.Now()
+	l.mu.Unlock()
+}
+
+// startRefresh reports whether the caller should refresh the cache, and
+// whether it is the first refresh, which callers should wait for.
+// A forced refresh is always done, and waited for
+func (l *locationCache) startRefresh(force bool) (refresh, wait bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if force {
+		l.refreshing = true
+		return true, true
+	}
+	if l.refreshing || time.Since(l.refreshed) < l.interval {
+		return false, false
+	}
+	l.refreshing = true
+	return true, l.refreshed.IsZero()
+}
+
+// endRefresh updates the endpoints from the account read
+func (l *locationCache) endRefresh(account *DatabaseAccount) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refreshing = false
+	l.refreshed = time.Now()
+	if account == nil {
+		return
+	}
+	if writes := l.order(account.WritableLocations, account.EnableMultipleWriteLocations); len(writes) > 0 {
+		l.writes = writes
+	}
+	if reads := l.order(account.ReadableLocations, true); len(reads) > 0 {
+		l.reads = reads
+	}
+}
+
+// order sorts locations by the preferred locations, followed by the other
+// ones in the order of the account. When preferred is false, the account order
+// is kept (i.e: single write region accounts)
+func (l *locationCache) order(locations []Location, preferred bool) []*url.URL {
+	var ordered []Location
+	if preferred {
+		for _, name := range l.preferred {
+			for _, loc := range locations {
+				if strings.EqualFold(normalizeLocation(loc.Name), normalizeLocation(name)) {
+					ordered = append(ordered, loc)
+				}
+			}
+		}
+	}
+	for _, loc := range locations {
+		if !containsLocation(ordered, loc) {
+			ordered = append(ordered, loc)
+		}
+	}
+	urls := make([]*url.URL, 0, len(ordered))
+	for _, loc := range ordered {
+		if u, err := url.Parse(loc.Endpoint); err == nil && u.Host != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// normalizeLocation allows both "West US" and "westus" to be used
+func normalizeLocation(name string) string {
+	return strings.Replace(name, " ", "", -1)
+}
+
+func containsLocation(locations []Location, loc Location) bool {
+	for _, l := range locations {
+		if l.Endpoint == loc.Endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// route sends the request to the most preferred endpoint that wasn't tried
+// yet, it returns the chosen endpoint
+func (c *Client) route(r *Request, tried []*url.URL) *url.URL {
+	if c.locations == nil {
+		return nil
+	}
+	c.refreshLocations(r.Context(), false)
+	endpoints := c.locations.endpoints(r.isRead())
+	endpoint := endpoints[len(tried)%len(endpoints)]
+	for _, u := range endpoints {
+		if !containsURL(tried, u) {
+			endpoint = u
+			break
+		}
+	}
+	r.URL.Scheme = endpoint.Scheme
+	r.URL.Host = endpoint.Host
+	r.Host = endpoint.Host
+	return endpoint
+}
+
+func containsURL(urls []*url.URL, u *url.URL) bool {
+	for _, v := range urls {
+		if v.Host == u.Host {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshLocations reads the regions of the account if the cache expired, or
+// if force is set. The first read blocks the caller, the following ones run in
+// the background, and are bounded by the refresh interval
+func (c *Client) refreshLocations(ctx context.Context, force bool) {
+	refresh, wait := c.locations.startRefresh(force)
+	if !refresh {
+		return
+	}
+	if !wait {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), c.locations.interval)
+			defer cancel()
+			c.locations.endRefresh(c.discover(ctx))
+		}()
+		return
+	}
+	c.locations.endRefresh(c.discover(ctx))
+}
+
+// discover reads the database account from the default endpoint, or from
+// one of the known regional endpoints if it's not reachable
+func (c *Client) discover(ctx context.Context) *DatabaseAccount {
+	candidates := append([]*url.URL{c.locations.fallback}, c.locations.endpoints(true)...)
+	for _, endpoint := range candidates {
+		account, err := c.readAccount(ctx, endpoint.Scheme+"://"+endpoint.Host+"/")
+		if err == nil {
+			return account
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readAccount reads the database account from the given endpoint,
+// without routing or retries
+func (c *Client) readAccount(ctx context.Context, endpoint string) (*DatabaseAccount, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	r := ResourceRequest("", req)
+	if err = r.DefaultHeaders(c.Config, c.UserAgent); err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(r.Request)
+	if err != nil {
+		return nil, err
+	}
+	var account *DatabaseAccount
+	if _, err = c.handle(resp, expectStatusCode(http.StatusOK), &account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// failover reports whether the request should be sent to the next endpoint,
+// after a network error or a response telling the region is not available.
+// Writes are sent again only if the service rejected them.
+func (c *Client) failover(r *Request, endpoint *url.URL, resp *http.Response, err error, tried []*url.URL) bool {
+	if c.locations == nil || r.Context().Err() != nil {
+		return false
+	}
+	if resp != nil && resp.StatusCode == http.StatusForbidden && resp.Header.Get(HeaderSubStatus) == "3" {
+		// The write region changed, read the account again and send the write
+		// once to the new write region, or to the same one if it's the only one
+		if r.rerouted {
+			return false
+		}
+		c.refreshLocations(r.Context(), true)
+		r.rerouted = true
+		return true
+	}
+	if len(tried) >= len(c.locations.endpoints(r.isRead())) {
+		return false
+	}
+	switch {
+	case err != nil:
+		if !r.isRead() {
+			return false
+		}
+	case resp.StatusCode == http.StatusServiceUnavailable:
+	default:
+		return false
+	}
+	c.locations.markUnavailable(endpoint)
+	return true
+}
@@ -0,0 +1,143 @@
+package documentdb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// regionServer is a regional endpoint answering with status
+type regionServer struct {
+	*MockServer
+	calls  int
+	status int
+}
+
+func newRegionServer() *regionServer {
+	s := &regionServer{status: http.StatusOK}
+	s.MockServer = HandlerFactory(func(w http.ResponseWriter, r *http.Request) {
+		s.calls++
+		if r.Method == http.MethodPost && s.status == http.StatusOK {
+			w.WriteHeader(http.StatusCreated)
+		} else if s.status != http.StatusOK {
+			if s.status == http.StatusForbidden {
+				w.Header().Set(HeaderSubStatus, "3")
+			}
+			w.WriteHeader(s.status)
+		}
+		fmt.Fprintln(w, `{"id": "9"}`)
+	})
+	return s
+}
+
+func TestEndpointDiscovery(t *testing.T) {
+	assert := assert.New(t)
+	west, east := newRegionServer(), newRegionServer()
+	defer west.Close()
+	defer east.Close()
+	var accountReads int
+	writeRegion := west
+	account := HandlerFactory(func(w http.ResponseWriter, r *http.Request) {
+		accountReads++
+		fmt.Fprintf(w, `{
+			"writableLocations": [{"name": "West US", "databaseAccountEndpoint": "%[3]s"}],
+			"readableLocations": [{"name": "West US", "databaseAccountEndpoint": "%[1]s"}, {"name": "East US", "databaseAccountEndpoint": "%[2]s"}]
+		}`, west.URL, east.URL, writeRegion.URL)
+	})
+	defer account.Close()
+	db := New(account.URL, NewConfig(&Key{Key: "YXJpZWwNCg=="}).WithEndpointDiscovery("eastus"))
+
+	var doc Document
+	assert.Nil(db.ReadDocument("dbs/db/colls/coll/docs/9", &doc))
+	assert.Equal(1, accountReads, "Should read the account once")
+	assert.Equal(1, east.calls, "Reads should go to the preferred location")
+
+	_, err := db.CreateDocument("dbs/db/colls/coll/", &doc)
+	assert.Nil(err)
+	assert.Equal(1, west.calls, "Writes should go to the write region")
+
+	// Fail over to the next region
+	east.status = http.StatusServiceUnavailable
+	assert.Nil(db.ReadDocument("dbs/db/colls/coll/docs/9", &doc))
+	assert.Equal(2, east.calls)
+	assert.Equal(2, west.calls)
+
+	// The failed region is moved to the end
+	assert.Nil(db.ReadDocument("dbs/db/colls/coll/docs/9", &doc))
+	assert.Equal(2, east.calls)
+	assert.Equal(3, west.calls)
+
+	// Write forbidden refreshes the account and sends the write to the new write region
+	east.status = http.StatusOK
+	west.status = http.StatusForbidden
+	writeRegion = east
+	_, err = db.CreateDocument("dbs/db/colls/coll/", &doc)
+	assert.Nil(err)
+	assert.Equal(2, accountReads, "Write forbidden should refresh the account")
+	assert.Equal(4, west.calls)
+	assert.Equal(3, east.calls)
+
+	// The write is sent once again, to the same region if it's the only one
+	east.status = http.StatusForbidden
+	_, err = db.CreateDocument("dbs/db/colls/coll/", &doc)
+	var reqErr *RequestError
+	assert.True(errors.As(err, &reqErr))
+	assert.Equal(http.StatusForbidden, reqErr.StatusCode)
+	assert.Equal(3, accountReads)
+	assert.Equal(5, east.calls)
+}
+
+func TestEndpointDiscoveryConfig(t *testing.T) {
+	assert := assert.New(t)
+	db := New("https://account.documents.azure.com", NewConfig(&Key{Key: "YXJpZWwNCg=="}).WithEndpointDiscovery(""))
+	_, err := db.ReadDatabase("dbs/db")
+	assert.EqualError(err, "documentdb: empty preferred location")
+
+	db = New("account", NewConfig(&Key{Key: "YXJpZWwNCg=="}).WithEndpointDiscovery())
+	_, err = db.ReadDatabase("dbs/db")
+	assert.EqualError(err, `documentdb: invalid endpoint "account"`)
+}
+
+func TestLocationCacheOrder(t *testing.T) {
+	assert := assert.New(t)
+	l, err := newLocationCache("https://account.documents.azure.com", []string{"North Europe", "westus"}, 0)
+	assert.Nil(err)
+	assert.Equal("account.documents.azure.com", l.endpoints(true)[0].Host, "Should use the default endpoint before discovery")
+
+	l.endRefresh(&DatabaseAccount{
+		EnableMultipleWriteLocations: true,
+		WritableLocations: []Location{
+			{"East US", "https://account-eastus.documents.azure.com:443/"},
+			{"West US", "https://account-westus.documents.azure.com:443/"},
+		},
+		ReadableLocations: []Location{
+			{"East US", "https://account-eastus.documents.azure.com:443/"},
+			{"West US", "https://account-westus.documents.azure.com:443/"},
+			{"North Europe", "https://account-northeurope.documents.azure.com:443/"},
+		},
+	})
+	hosts := func(read bool) (h []string) {
+		for _, u := range l.endpoints(read) {
+			h = append(h, u.Host)
+		}
+		return
+	}
+	assert.Equal([]string{
+		"account-northeurope.documents.azure.com:443",
+		"account-westus.documents.azure.com:443",
+		"account-eastus.documents.azure.com:443",
+	}, hosts(true))
+	assert.Equal([]string{
+		"account-westus.documents.azure.com:443",
+		"account-eastus.documents.azure.com:443",
+	}, hosts(false), "Multi write accounts should use the preferred locations")
+
+	l.markUnavailable(l.endpoints(false)[0])
+	assert.Equal([]string{
+		"account-eastus.documents.azure.com:443",
+		"account-westus.documents.azure.com:443",
+	}, hosts(false))
+}
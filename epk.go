@@ -0,0 +1,330 @@
+package documentdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// Undefined is the value of a partition key path missing from a document
+type Undefined struct{}
+
+// Markers of the partition key components
+const (
+	componentUndefined byte = 0x00
+	componentNull      byte = 0x01
+	componentFalse     byte = 0x02
+	componentTrue      byte = 0x03
+	componentNumber    byte = 0x05
+	componentString    byte = 0x08
+)
+
+// maxStringLength is the number of characters of a string component kept by
+// the hash V1
+const maxStringLength = 100
+
+// EffectivePartitionKey returns the effective partition key of the partition key
+// values of a document, the hex string that locates its partition key range.
+// The values are strings, numbers, booleans, nil or Undefined, one per path of
+// the definition, a prefix of the paths is accepted by hierarchical keys
+func EffectivePartitionKey(def PartitionKeyDefinition, values ...interface{}) (string, error) {
+	if len(values) == 0 {
+		return MinEffectivePartitionKey, nil
+	}
+	if len(def.Paths) > 0 && len(values) > len(def.Paths) {
+		return "", fmt.Errorf("documentdb: %d partition key values for %d paths", len(values), len(def.Paths))
+	}
+	components, err := partitionKeyComponents(values)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case def.Kind == PartitionKindMultiHash:
+		// Each level is hashed on its own
+		var epk strings.Builder
+		for _, c := range components {
+			epk.WriteString(hashV2([]interface{}{c}))
+		}
+		return epk.String(), nil
+	case def.Version == 2:
+		return hashV2(components), nil
+	}
+	return hashV1(components), nil
+}
+
+// partitionKeyComponents normalizes the values, numbers are float64
+func partitionKeyComponents(values []interface{}) ([]interface{}, error) {
+	components := make([]interface{}, len(values))
+	for i, v := range values {
+		switch n := v.(type) {
+		case nil, bool, string, Undefined:
+			components[i] = v
+		case float64:
+			components[i] = n
+		case float32:
+			components[i] = float64(n)
+		case int:
+			components[i] = float64(n)
+		case int8:
+			components[i] = float64(n)
+		case int16:
+			components[i] = float64(n)
+		case int32:
+			components[i] = float64(n)
+		case int64:
+			components[i] = float64(n)
+		case uint:
+			components[i] = float64(n)
+		case uint8:
+			components[i] = float64(n)
+		case uint16:
+			components[i] = float64(n)
+		case uint32:
+			components[i] = float64(n)
+		case uint64:
+			components[i] = float64(n)
+		case json.Number:
+			f, err := n.Float64()
+			if err != nil {
+				return nil, err
+			}
+			components[i] = f
+		default:
+			return nil, fmt.Errorf("documentdb: unsupported partition key value of type %T", v)
+		}
+	}
+	return components, nil
+}
+
+// hashV1 returns the MurmurHash3 32-bit hash of the components, followed by the
+// components, the strings are truncated to 100 characters for both
+func hashV1(components []interface{}) string {
+	truncated := make([]interface{}, len(components))
+	for i, c := range components {
+		if s, ok := c.(string); ok {
+			c = truncateString(s, maxStringLength)
+		}
+		truncated[i] = c
+	}
+	components = truncated
+
+	var buf bytes.Buffer
+	for _, c := range components {
+		writeForHashing(&buf, c, 0x00)
+	}
+	hash := float64(murmur3_32(buf.Bytes(), 0))
+
+	buf.Reset()
+	writeForBinaryEncoding(&buf, hash)
+	for _, c := range components {
+		writeForBinaryEncoding(&buf, c)
+	}
+	return strings.ToUpper(hex.EncodeToString(buf.Bytes()))
+}
+
+// hashV2 returns the MurmurHash3 x64 128-bit hash of the components, without
+// its 2 most significant bits
+func hashV2(components []interface{}) string {
+	var buf bytes.Buffer
+	for _, c := range components {
+		writeForHashing(&buf, c, 0xFF)
+	}
+	h1, h2 := murmur3_128(buf.Bytes(), 0)
+	hash := make([]byte, 16)
+	binary.BigEndian.PutUint64(hash, h2)
+	binary.BigEndian.PutUint64(hash[8:], h1)
+	hash[0] &= 0x3F
+	return strings.ToUpper(hex.EncodeToString(hash))
+}
+
+// writeForHashing writes the component hashed by the effective partition key,
+// the strings end with terminator
+func writeForHashing(buf *bytes.Buffer, c interface{}, terminator byte) {
+	switch v := c.(type) {
+	case Undefined:
+		buf.WriteByte(componentUndefined)
+	case nil:
+		buf.WriteByte(componentNull)
+	case bool:
+		if v {
+			buf.WriteByte(componentTrue)
+		} else {
+			buf.WriteByte(componentFalse)
+		}
+	case float64:
+		buf.WriteByte(componentNumber)
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+		buf.Write(b[:])
+	case string:
+		buf.WriteByte(componentString)
+		buf.WriteString(v)
+		buf.WriteByte(terminator)
+	}
+}
+
+// writeForBinaryEncoding writes the component in the order preserving encoding
+// of the effective partition key
+func writeForBinaryEncoding(buf *bytes.Buffer, c interface{}) {
+	switch v := c.(type) {
+	case float64:
+		buf.WriteByte(componentNumber)
+		payload := math.Float64bits(v)
+		if v < 0 {
+			payload = ^payload
+		} else {
+			payload ^= 1 << 63
+		}
+		// The first byte, then 7 bits per byte, the lowest bit is set on all
+		// the bytes but the last one
+		buf.WriteByte(byte(payload >> 56))
+		payload <<= 8
+		for {
+			b := byte(payload>>56) | 0x01
+			payload <<= 7
+			if payload == 0 {
+				buf.WriteByte(b & 0xFE)
+				return
+			}
+			buf.WriteByte(b)
+		}
+	case string:
+		buf.WriteByte(componentString)
+		for i := 0; i < len(v); i++ {
+			b := v[i]
+			if b < 0xFF {
+				b++
+			}
+			buf.WriteByte(b)
+		}
+		buf.WriteByte(0x00)
+	default:
+		writeForHashing(buf, c, 0x00)
+	}
+}
+
+// truncateString returns the first n characters of s, it never cuts a rune
+func truncateString(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// murmur3_32 is the MurmurHash3 x86 32-bit hash
+func murmur3_32(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	h := seed
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+	tail := data[n*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// murmur3_128 is the MurmurHash3 x64 128-bit hash
+func murmur3_128(data []byte, seed uint64) (h1, h2 uint64) {
+	const (
+		c1 = 0x87c37b91114253d5
+		c2 = 0x4cf5ad432745937f
+	)
+	h1, h2 = seed, seed
+	n := len(data) / 16
+	for i := 0; i < n; i++ {
+		k1 := binary.LittleEndian.Uint64(data[i*16:])
+		k2 := binary.LittleEndian.Uint64(data[i*16+8:])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+	tail := data[n*16:]
+	var k1, k2 uint64
+	for i := len(tail) - 1; i >= 8; i-- {
+		k2 ^= uint64(tail[i]) << (uint(i-8) * 8)
+	}
+	if len(tail) > 8 {
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+	}
+	for i := 0; i < len(tail) && i < 8; i++ {
+		k1 ^= uint64(tail[i]) << (uint(i) * 8)
+	}
+	if len(tail) > 0 {
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(len(data))
+	h2 ^= uint64(len(data))
+	h1 += h2
+	h2 += h1
+	h1, h2 = fmix64(h1), fmix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
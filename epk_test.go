@@ -0,0 +1,110 @@
+package documentdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectivePartitionKeyV1(t *testing.T) {
+	assert := assert.New(t)
+	def := PartitionKeyDefinition{Paths: []string{"/pk"}, Kind: PartitionKindHash}
+	for _, c := range []struct {
+		value interface{}
+		epk   string
+	}{
+		{"", "05C1CF33970FF80800"},
+		{"partitionKey", "05C1E1B3D9CD2608716273756A756A706F4C667A00"},
+		{nil, "05C1ED45D7475601"},
+		{true, "05C1D7C5A903D803"},
+		{false, "05C1DB857D857C02"},
+	} {
+		epk, err := EffectivePartitionKey(def, c.value)
+		assert.Nil(err)
+		assert.Equal(c.epk, epk, "%v", c.value)
+	}
+}
+
+func TestEffectivePartitionKeyV2(t *testing.T) {
+	assert := assert.New(t)
+	def := PartitionKeyDefinition{Paths: []string{"/pk"}, Kind: PartitionKindHash, Version: 2}
+	for _, c := range []struct {
+		value interface{}
+		epk   string
+	}{
+		{"", "32E9366E637A71B4E710384B2F4970A0"},
+		{"partitionKey", "013AEFCF77FA271571CF665A58C933F1"},
+		{nil, "378867E4430E67857ACE5C908374FE16"},
+		{true, "0E711127C5B5A8E4726AC6DD306A3E59"},
+		{false, "2FE1BE91E90A3439635E0E9E37361EF2"},
+	} {
+		epk, err := EffectivePartitionKey(def, c.value)
+		assert.Nil(err)
+		assert.Equal(c.epk, epk, "%v", c.value)
+		assert.True(epk < MaxEffectivePartitionKey)
+	}
+}
+
+func TestEffectivePartitionKeyNumbers(t *testing.T) {
+	assert := assert.New(t)
+	for _, def := range []PartitionKeyDefinition{{Version: 1}, {Version: 2}} {
+		a, err := EffectivePartitionKey(def, 5)
+		assert.Nil(err)
+		b, _ := EffectivePartitionKey(def, 5.0)
+		c, _ := EffectivePartitionKey(def, uint8(5))
+		d, _ := EffectivePartitionKey(def, 6)
+		assert.Equal(a, b)
+		assert.Equal(a, c)
+		assert.NotEqual(a, d)
+	}
+}
+
+func TestEffectivePartitionKeyHierarchical(t *testing.T) {
+	assert := assert.New(t)
+	def := PartitionKeyDefinition{Paths: []string{"/tenant", "/user"}, Kind: PartitionKindMultiHash, Version: 2}
+	tenant, err := EffectivePartitionKey(def, "partitionKey")
+	assert.Nil(err)
+	assert.Equal("013AEFCF77FA271571CF665A58C933F1", tenant)
+
+	full, err := EffectivePartitionKey(def, "partitionKey", true)
+	assert.Nil(err)
+	assert.Equal("013AEFCF77FA271571CF665A58C933F1"+"0E711127C5B5A8E4726AC6DD306A3E59", full)
+	assert.True(strings.HasPrefix(full, tenant), "the keys of a prefix are in the range of the prefix")
+
+	_, err = EffectivePartitionKey(def, "a", "b", "c")
+	assert.NotNil(err)
+}
+
+func TestEffectivePartitionKeyErrors(t *testing.T) {
+	assert := assert.New(t)
+	epk, err := EffectivePartitionKey(PartitionKeyDefinition{})
+	assert.Nil(err)
+	assert.Equal(MinEffectivePartitionKey, epk)
+
+	_, err = EffectivePartitionKey(PartitionKeyDefinition{}, []string{"a"})
+	assert.EqualError(err, "documentdb: unsupported partition key value of type []string")
+
+	undefined, err := EffectivePartitionKey(PartitionKeyDefinition{}, Undefined{})
+	assert.Nil(err)
+	null, _ := EffectivePartitionKey(PartitionKeyDefinition{}, nil)
+	assert.NotEqual(undefined, null)
+}
+
+func TestEffectivePartitionKeyLongStrings(t *testing.T) {
+	assert := assert.New(t)
+	// V1 keeps the first 100 characters, for both the hash and the encoding
+	epk, err := EffectivePartitionKey(PartitionKeyDefinition{}, strings.Repeat("a", 1024))
+	assert.Nil(err)
+	assert.Equal("05C1EB5921F70608"+strings.Repeat("62", 100)+"00", epk)
+	a, _ := EffectivePartitionKey(PartitionKeyDefinition{}, strings.Repeat("a", 100)+"b")
+	assert.Equal(epk, a)
+	// The truncation never cuts a rune
+	a, _ = EffectivePartitionKey(PartitionKeyDefinition{}, strings.Repeat("é", 101))
+	b, _ := EffectivePartitionKey(PartitionKeyDefinition{}, strings.Repeat("é", 100))
+	assert.Equal(b, a)
+	// V2 hashes the whole string
+	a, _ = EffectivePartitionKey(PartitionKeyDefinition{Version: 2}, strings.Repeat("a", 100)+"b")
+	b, _ = EffectivePartitionKey(PartitionKeyDefinition{Version: 2}, strings.Repeat("a", 100)+"c")
+	assert.NotEqual(a, b)
+}
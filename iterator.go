@@ -1,5 +1,7 @@
 package documentdb
 
+import "context"
+
 // Iterator allows easily fetch multiple result sets when response max item limit is reacheds
 type Iterator struct {
 	continuationToken string
@@ -31,10 +33,15 @@ func (di *Iterator) Error() error {
 
 // Next will ask iterator source for results and checks whenever there some more pages left
 func (di *Iterator) Next() bool {
+	return di.NextWithContext(context.Background())
+}
+
+// NextWithContext is like Next, but the request for the next page is bound to ctx
+func (di *Iterator) NextWithContext(ctx context.Context) bool {
 	if !di.next {
 		return false
 	}
-	di.response, di.err = di.source(di.db, Continuation(di.continuationToken))
+	di.response, di.err = di.source(di.db, Continuation(di.continuationToken), withContext(ctx))
 	if di.err != nil {
 		return false
 	}
@@ -9,11 +9,93 @@ type Resource struct {
 	Ts   int    `json:"_ts,omitempty"`
 }
 
+// IndexingMode
+type IndexingMode string
+
+const (
+	IndexingConsistent IndexingMode = "consistent"
+	IndexingLazy       IndexingMode = "lazy"
+	// IndexingNone disables indexing, it requires Automatic to be false
+	IndexingNone IndexingMode = "none"
+)
+
 // Indexing policy
-// TODO: Ex/IncludePaths
 type IndexingPolicy struct {
-	IndexingMode string `json: "indexingMode,omitempty"`
-	Automatic    bool   `json: "automatic,omitempty"`
+	IndexingMode IndexingMode `json:"indexingMode,omitempty"`
+	// Automatic indexes all documents unless they opt out, nil leaves the service default (true)
+	Automatic        *bool              `json:"automatic,omitempty"`
+	IncludedPaths    []IncludedPath     `json:"includedPaths,omitempty"`
+	ExcludedPaths    []ExcludedPath     `json:"excludedPaths,omitempty"`
+	CompositeIndexes [][]CompositeIndex `json:"compositeIndexes,omitempty"`
+	SpatialIndexes   []SpatialIndex     `json:"spatialIndexes,omitempty"`
+}
+
+// IncludedPath is a path to index, e.g: "/*" or "/address/city/?"
+type IncludedPath struct {
+	Path string `json:"path"`
+	// Indexes is only used by the legacy (hash and range) indexing policies
+	Indexes []Index `json:"indexes,omitempty"`
+}
+
+// Index is a legacy index of an included path
+type Index struct {
+	Kind      string `json:"kind,omitempty"`
+	DataType  string `json:"dataType,omitempty"`
+	Precision int    `json:"precision,omitempty"`
+}
+
+// ExcludedPath is a path to skip, e.g: "/\"_etag\"/?"
+type ExcludedPath struct {
+	Path string `json:"path"`
+}
+
+// IndexOrder
+type IndexOrder string
+
+const (
+	Ascending  IndexOrder = "ascending"
+	Descending IndexOrder = "descending"
+)
+
+// CompositeIndex is one path of a composite index
+type CompositeIndex struct {
+	Path  string     `json:"path"`
+	Order IndexOrder `json:"order,omitempty"`
+}
+
+// SpatialIndex
+type SpatialIndex struct {
+	Path string `json:"path"`
+	// Types of the indexed geometries, e.g: "Point", "Polygon"
+	Types []string `json:"types,omitempty"`
+}
+
+// PartitionKind
+type PartitionKind string
+
+const (
+	PartitionKindHash PartitionKind = "Hash"
+	// PartitionKindMultiHash is used by hierarchical partition keys
+	PartitionKindMultiHash PartitionKind = "MultiHash"
+)
+
+// PartitionKeyDefinition
+type PartitionKeyDefinition struct {
+	Paths []string      `json:"paths"`
+	Kind  PartitionKind `json:"kind,omitempty"`
+	// Version of the partition key hashing, 2 supports keys longer than 100 bytes
+	Version   int   `json:"version,omitempty"`
+	SystemKey *bool `json:"systemKey,omitempty"`
+}
+
+// UniqueKeyPolicy
+type UniqueKeyPolicy struct {
+	UniqueKeys []UniqueKey `json:"uniqueKeys"`
+}
+
+// UniqueKey is a set of paths that is unique within a logical partition
+type UniqueKey struct {
+	Paths []string `json:"paths"`
 }
 
 // Database
@@ -37,12 +119,49 @@ func (d Databases) First() *Database {
 // Collection
 type Collection struct {
 	Resource
-	IndexingPolicy IndexingPolicy `json:"indexingPolicy,omitempty"`
-	Docs           string         `json:"_docs,omitempty"`
-	Udf            string         `json:"_udfs,omitempty"`
-	Sporcs         string         `json:"_sporcs,omitempty"`
-	Triggers       string         `json:"_triggers,omitempty"`
-	Conflicts      string         `json:"_conflicts,omitempty"`
+	IndexingPolicy  IndexingPolicy          `json:"indexingPolicy,omitempty"`
+	PartitionKey    *PartitionKeyDefinition `json:"partitionKey,omitempty"`
+	UniqueKeyPolicy *UniqueKeyPolicy        `json:"uniqueKeyPolicy,omitempty"`
+	// DefaultTTL in seconds, -1 enables TTL without expiring documents by default
+	DefaultTTL *int   `json:"defaultTtl,omitempty"`
+	Docs       string `json:"_docs,omitempty"`
+	Udf        string `json:"_udfs,omitempty"`
+	Sporcs     string `json:"_sprocs,omitempty"`
+	Triggers   string `json:"_triggers,omitempty"`
+	Conflicts  string `json:"_conflicts,omitempty"`
+	// ConflictResolutionPolicy applies to accounts with multiple write regions
+	ConflictResolutionPolicy *ConflictResolutionPolicy `json:"conflictResolutionPolicy,omitempty"`
+}
+
+// ConflictResolutionMode is the way conflicting writes are resolved
+type ConflictResolutionMode string
+
+const (
+	// ConflictLastWriterWins keeps the write with the highest value of the resolution path
+	ConflictLastWriterWins ConflictResolutionMode = "LastWriterWins"
+	// ConflictCustom resolves conflicts with a stored procedure, unresolved
+	// conflicts are written to the conflicts feed
+	ConflictCustom ConflictResolutionMode = "Custom"
+)
+
+// ConflictResolutionPolicy
+type ConflictResolutionPolicy struct {
+	Mode ConflictResolutionMode `json:"mode,omitempty"`
+	// ConflictResolutionPath is the numeric property used by LastWriterWins, "/_ts" by default
+	ConflictResolutionPath string `json:"conflictResolutionPath,omitempty"`
+	// ConflictResolutionProcedure is the stored procedure link used by Custom
+	ConflictResolutionProcedure string `json:"conflictResolutionProcedure,omitempty"`
+}
+
+// Conflict is a write that lost against another region
+type Conflict struct {
+	Resource
+	ResourceType  string `json:"resourceType,omitempty"`
+	OperationType string `json:"operationType,omitempty"`
+	// SourceResourceId is the _rid of the conflicting resource
+	SourceResourceId string `json:"resourceId,omitempty"`
+	// Content is the conflicting resource, serialized as json
+	Content string `json:"content,omitempty"`
 }
 
 // Collection slice of Collection elements
@@ -59,7 +178,43 @@ func (c Collections) First() *Collection {
 // Document
 type Document struct {
 	Resource
-	attachments string `json:"attachments,omitempty"`
+	Attachments string `json:"_attachments,omitempty"`
+}
+
+// Attachment
+type Attachment struct {
+	Resource
+	ContentType string `json:"contentType,omitempty"`
+	// Media is the link of the attachment content, either stored by the
+	// service ("/media/{id}") or external
+	Media string `json:"media,omitempty"`
+}
+
+// Offer is the throughput provisioned for a database or a collection
+type Offer struct {
+	Resource
+	OfferVersion string       `json:"offerVersion,omitempty"`
+	OfferType    string       `json:"offerType,omitempty"`
+	Content      OfferContent `json:"content"`
+	// ResourceLink is the self link of the database or collection
+	ResourceLink string `json:"resource,omitempty"`
+	// OfferResourceId is the _rid of the database or collection
+	OfferResourceId string `json:"offerResourceId,omitempty"`
+}
+
+// OfferContent
+type OfferContent struct {
+	// OfferThroughput is the provisioned RU/s, for autoscale offers it is the
+	// current throughput
+	OfferThroughput                     int                `json:"offerThroughput,omitempty"`
+	AutoscaleSettings                   *AutoscaleSettings `json:"offerAutopilotSettings,omitempty"`
+	OfferIsRUPerMinuteThroughputEnabled *bool              `json:"offerIsRUPerMinuteThroughputEnabled,omitempty"`
+}
+
+// AutoscaleSettings
+type AutoscaleSettings struct {
+	// MaxThroughput is the RU/s the resource scales up to, it scales down to 10% of it
+	MaxThroughput int `json:"maxThroughput"`
 }
 
 // Stored Procedure
@@ -74,10 +229,115 @@ type UDF struct {
 	Body string `json:"body,omitempty"`
 }
 
+// TriggerType defines when a trigger is executed
+type TriggerType string
+
+const (
+	// TriggerPre is executed before the operation
+	TriggerPre TriggerType = "Pre"
+
+	// TriggerPost is executed after the operation
+	TriggerPost TriggerType = "Post"
+)
+
+// TriggerOperation defines the operations a trigger is executed for
+type TriggerOperation string
+
+const (
+	TriggerAll     TriggerOperation = "All"
+	TriggerCreate  TriggerOperation = "Create"
+	TriggerReplace TriggerOperation = "Replace"
+	TriggerDelete  TriggerOperation = "Delete"
+)
+
+// Trigger
+type Trigger struct {
+	Resource
+	Body             string           `json:"body,omitempty"`
+	TriggerType      TriggerType      `json:"triggerType,omitempty"`
+	TriggerOperation TriggerOperation `json:"triggerOperation,omitempty"`
+}
+
+// User
+type User struct {
+	Resource
+	Permissions string `json:"_permissions,omitempty"`
+}
+
+// PermissionMode is the access mode a permission grants
+type PermissionMode string
+
+const (
+	// PermissionRead grants read access to the resource
+	PermissionRead PermissionMode = "Read"
+
+	// PermissionAll grants read, write and delete access to the resource
+	PermissionAll PermissionMode = "All"
+)
+
+// Permission
+type Permission struct {
+	Resource
+	PermissionMode       PermissionMode `json:"permissionMode,omitempty"`
+	ResourceLink         string         `json:"resource,omitempty"`
+	ResourcePartitionKey []interface{}  `json:"resourcePartitionKey,omitempty"`
+	Token                string         `json:"_token,omitempty"`
+}
+
 // PartitionKeyRange partition key range model
 type PartitionKeyRange struct {
 	Resource
 	PartitionKeyRangeID string `json:"id,omitempty"`
 	MinInclusive        string `json:"minInclusive,omitempty"`
 	MaxInclusive        string `json:"maxExclusive,omitempty"`
+	// Parents are the ids of the ranges this range was split or merged from
+	Parents []string `json:"parents,omitempty"`
+}
+
+// DatabaseAccount
+type DatabaseAccount struct {
+	Resource
+	Dbs                          string             `json:"_dbs,omitempty"`
+	Media                        string             `json:"media,omitempty"`
+	Addresses                    string             `json:"addresses,omitempty"`
+	WritableLocations            []Location         `json:"writableLocations,omitempty"`
+	ReadableLocations            []Location         `json:"readableLocations,omitempty"`
+	EnableMultipleWriteLocations bool               `json:"enableMultipleWriteLocations,omitempty"`
+	ConsistencyPolicy            *ConsistencyPolicy `json:"userConsistencyPolicy,omitempty"`
+	ReplicationPolicy            *ReplicationPolicy `json:"userReplicationPolicy,omitempty"`
+	SystemReplicationPolicy      *ReplicationPolicy `json:"systemReplicationPolicy,omitempty"`
+	ReadPolicy                   *ReadPolicy        `json:"readPolicy,omitempty"`
+	// QueryEngineConfiguration is the json encoded configuration of the query engine
+	QueryEngineConfiguration string `json:"queryEngineConfiguration,omitempty"`
+
+	// Media quota, in MB, filled from the response headers
+	MaxMediaStorageUsageMB int64 `json:"-"`
+	MediaStorageUsageMB    int64 `json:"-"`
+}
+
+// ConsistencyPolicy is the default consistency of an account
+type ConsistencyPolicy struct {
+	DefaultConsistencyLevel Consistency `json:"defaultConsistencyLevel"`
+	// Staleness bounds of the Bounded consistency
+	MaxStalenessPrefix            int `json:"maxStalenessPrefix,omitempty"`
+	MaxStalenessIntervalInSeconds int `json:"maxStalenessIntervalInSeconds,omitempty"`
+}
+
+// ReplicationPolicy
+type ReplicationPolicy struct {
+	AsyncReplication  bool `json:"asyncReplication"`
+	MinReplicaSetSize int  `json:"minReplicaSetSize,omitempty"`
+	MaxReplicaSetSize int  `json:"maxReplicasetSize,omitempty"`
+}
+
+// ReadPolicy
+type ReadPolicy struct {
+	PrimaryReadCoefficient   int `json:"primaryReadCoefficient"`
+	SecondaryReadCoefficient int `json:"secondaryReadCoefficient"`
+}
+
+// Location is a region of a database account
+type Location struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"databaseAccountEndpoint"`
 }
@@ -0,0 +1,47 @@
+package documentdb
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	body := `{
+		"id": "orders",
+		"indexingPolicy": {
+			"indexingMode": "consistent",
+			"automatic": false,
+			"includedPaths": [{"path": "/*"}, {"path": "/total/?", "indexes": [{"kind": "Range", "dataType": "Number", "precision": -1}]}],
+			"excludedPaths": [{"path": "/\"_etag\"/?"}],
+			"compositeIndexes": [[{"path": "/customer", "order": "ascending"}, {"path": "/total", "order": "descending"}]],
+			"spatialIndexes": [{"path": "/location/*", "types": ["Point", "Polygon"]}]
+		},
+		"partitionKey": {"paths": ["/tenant", "/customer"], "kind": "MultiHash", "version": 2},
+		"uniqueKeyPolicy": {"uniqueKeys": [{"paths": ["/email"]}]},
+		"defaultTtl": -1,
+		"_sprocs": "sprocs/"
+	}`
+	var coll Collection
+	assert.Nil(json.Unmarshal([]byte(body), &coll))
+	assert.Equal(IndexingConsistent, coll.IndexingPolicy.IndexingMode)
+	assert.False(*coll.IndexingPolicy.Automatic)
+	assert.Equal(-1, coll.IndexingPolicy.IncludedPaths[1].Indexes[0].Precision)
+	assert.Equal(Descending, coll.IndexingPolicy.CompositeIndexes[0][1].Order)
+	assert.Equal(PartitionKindMultiHash, coll.PartitionKey.Kind)
+	assert.Equal([]string{"/email"}, coll.UniqueKeyPolicy.UniqueKeys[0].Paths)
+	assert.Equal(-1, *coll.DefaultTTL)
+	assert.Equal("sprocs/", coll.Sporcs)
+
+	b, err := json.Marshal(&coll)
+	assert.Nil(err)
+	assert.JSONEq(body, string(b))
+}
+
+func TestCollectionOmitsDefaults(t *testing.T) {
+	b, err := json.Marshal(&Collection{Resource: Resource{Id: "coll"}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id":"coll","indexingPolicy":{}}`, string(b))
+}
@@ -0,0 +1,93 @@
+package documentdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// Read offer by self link
+func (c *DocumentDB) ReadOffer(link string, opts ...CallOption) (offer *Offer, err error) {
+	offer, _, err = c.ReadOfferWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadOfferWithContext reads offer by self link, the request is bound to ctx
+func (c *DocumentDB) ReadOfferWithContext(ctx context.Context, link string, opts ...CallOption) (offer *Offer, res *Response, err error) {
+	opts = append(opts, apiVersion(AutoscaleVersion))
+	res, err = c.client.ReadWithContext(ctx, link, &offer, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Read all offers of the account
+func (c *DocumentDB) ReadOffers(opts ...CallOption) (offers []Offer, err error) {
+	offers, _, err = c.ReadOffersWithContext(context.Background(), opts...)
+	return
+}
+
+// ReadOffersWithContext reads all offers of the account, the request is bound to ctx
+func (c *DocumentDB) ReadOffersWithContext(ctx context.Context, opts ...CallOption) (offers []Offer, res *Response, err error) {
+	return c.QueryOffersWithContext(ctx, nil, opts...)
+}
+
+// Read all offers that satisfy a query
+func (c *DocumentDB) QueryOffers(query *Query, opts ...CallOption) (offers []Offer, err error) {
+	offers, _, err = c.QueryOffersWithContext(context.Background(), query, opts...)
+	return
+}
+
+// QueryOffersWithContext reads all offers that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryOffersWithContext(ctx context.Context, query *Query, opts ...CallOption) (offers []Offer, res *Response, err error) {
+	data := struct {
+		Offers []Offer `json:"Offers,omitempty"`
+		Count  int     `json:"_count,omitempty"`
+	}{}
+	opts = append(opts, apiVersion(AutoscaleVersion))
+	if query != nil {
+		res, err = c.client.QueryWithContext(ctx, "offers/", query, &data, opts...)
+	} else {
+		res, err = c.client.ReadWithContext(ctx, "offers/", &data, opts...)
+	}
+	if offers = data.Offers; err != nil {
+		offers = nil
+	}
+	return
+}
+
+// Read the offer of a database or a collection by its _rid
+func (c *DocumentDB) ReadOfferByResource(rid string, opts ...CallOption) (offer *Offer, err error) {
+	offer, _, err = c.ReadOfferByResourceWithContext(context.Background(), rid, opts...)
+	return
+}
+
+// ReadOfferByResourceWithContext reads the offer of a database or a collection by its _rid,
+// the request is bound to ctx. Resources without dedicated throughput return ErrNotFound
+func (c *DocumentDB) ReadOfferByResourceWithContext(ctx context.Context, rid string, opts ...CallOption) (offer *Offer, res *Response, err error) {
+	query := NewQuery("SELECT * FROM ROOT r WHERE r.offerResourceId = @rid", P{"@rid", rid})
+	offers, res, err := c.QueryOffersWithContext(ctx, query, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	if len(offers) == 0 {
+		return nil, res, fmt.Errorf("offer of resource %q: %w", rid, ErrNotFound)
+	}
+	return &offers[0], res, nil
+}
+
+// Replace offer, i.e: change the provisioned or the autoscale max throughput
+func (c *DocumentDB) ReplaceOffer(link string, offer *Offer, opts ...CallOption) (*Offer, error) {
+	offer, _, err := c.ReplaceOfferWithContext(context.Background(), link, offer, opts...)
+	return offer, err
+}
+
+// ReplaceOfferWithContext replaces offer, the request is bound to ctx
+func (c *DocumentDB) ReplaceOfferWithContext(ctx context.Context, link string, offer *Offer, opts ...CallOption) (ret *Offer, res *Response, err error) {
+	opts = append(opts, apiVersion(AutoscaleVersion))
+	res, err = c.client.ReplaceWithContext(ctx, link, offer, &ret, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
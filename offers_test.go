@@ -0,0 +1,85 @@
+package documentdb
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReadOffer(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "offers/xb8x/", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadOffer("offers/xb8x/")
+	client.AssertCalled(t, "Read", "offers/xb8x/", mock.Anything, mock.Anything)
+}
+
+func TestReadOffers(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "offers/", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadOffers()
+	client.AssertCalled(t, "Read", "offers/", mock.Anything, mock.Anything)
+}
+
+func TestReadOfferByResource(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	q := NewQuery("SELECT * FROM ROOT r WHERE r.offerResourceId = @rid", P{"@rid", "b5NCAJk5AQA="})
+	client.On("Query", "offers/", q).Return(nil)
+	_, err := c.ReadOfferByResource("b5NCAJk5AQA=")
+	client.AssertCalled(t, "Query", "offers/", q)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestReplaceOffer(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	offer := &Offer{Content: OfferContent{OfferThroughput: 1000}}
+	client.On("Replace", "offers/xb8x/", offer).Return(nil)
+	c.ReplaceOffer("offers/xb8x/", offer)
+	client.AssertCalled(t, "Replace", "offers/xb8x/", offer)
+}
+
+func TestOfferHeaders(t *testing.T) {
+	assert := assert.New(t)
+	r, _ := http.NewRequest(http.MethodPost, "dbs/db/colls/", nil)
+	req := ResourceRequest("dbs/db/colls/", r)
+
+	assert.Nil(OfferThroughput(400)(req))
+	assert.Equal("400", req.Header.Get(HeaderOfferThroughput))
+
+	assert.Nil(AutoscaleMaxThroughput(4000)(req))
+	assert.Equal(`{"maxThroughput":4000}`, req.Header.Get(HeaderOfferAutoscale))
+	assert.Equal(AutoscaleVersion, req.Header.Get(HeaderVersion))
+}
+
+func TestOfferVersion(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"id": "xb8x"}`, `{"id": "xb8x"}`)
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+	c := &DocumentDB{client, client.Config}
+
+	_, err := c.ReadOffer("offers/xb8x/")
+	assert.Nil(err)
+	assert.Equal(AutoscaleVersion, s.Header.Get(HeaderVersion), "Offers should be read with the autoscale settings")
+
+	_, err = c.ReadCollection("dbs/db/colls/coll/")
+	assert.Nil(err)
+	assert.Equal(SupportedVersion, s.Header.Get(HeaderVersion))
+}
+
+func TestOfferRequest(t *testing.T) {
+	assert := assert.New(t)
+	req := ResourceRequest("offers/Xb8x/", &http.Request{})
+	assert.Equal("offers", req.rType)
+	assert.Equal("xb8x", req.rId)
+
+	req = ResourceRequest("offers/", &http.Request{})
+	assert.Equal("offers", req.rType)
+	assert.Equal("", req.rId)
+}
@@ -1,8 +1,10 @@
 package documentdb
 
 import (
+	"context"
 	"encoding/json"
 	"strconv"
+	"strings"
 )
 
 // Consistency type to define consistency levels
@@ -148,3 +150,112 @@ func ChangeFeedPartitionRangeID(id string) CallOption {
 		return nil
 	}
 }
+
+// PreTrigger sets the ids of the pre-triggers to execute with a document write
+func PreTrigger(ids ...string) CallOption {
+	header := strings.Join(ids, ",")
+	return func(r *Request) error {
+		r.Header.Set(HeaderPreTriggerInclude, header)
+		return nil
+	}
+}
+
+// PostTrigger sets the ids of the post-triggers to execute with a document write
+func PostTrigger(ids ...string) CallOption {
+	header := strings.Join(ids, ",")
+	return func(r *Request) error {
+		r.Header.Set(HeaderPostTriggerInclude, header)
+		return nil
+	}
+}
+
+// ResourceTokenExpiry sets the validity period, in seconds, of the resource token
+// returned with a permission (default is one hour, max is five hours)
+func ResourceTokenExpiry(seconds int) CallOption {
+	header := strconv.Itoa(seconds)
+	return func(r *Request) error {
+		r.Header.Set(HeaderExpirySeconds, header)
+		return nil
+	}
+}
+
+// OfferThroughput sets the RU/s provisioned for a database or a collection on creation
+func OfferThroughput(throughput int) CallOption {
+	header := strconv.Itoa(throughput)
+	return func(r *Request) error {
+		r.Header.Set(HeaderOfferThroughput, header)
+		return nil
+	}
+}
+
+// AutoscaleMaxThroughput sets the max RU/s of an autoscale database or collection on creation.
+// The request is sent with the AutoscaleVersion API version
+func AutoscaleMaxThroughput(maxThroughput int) CallOption {
+	return func(r *Request) error {
+		b, err := Serialization.Marshal(&AutoscaleSettings{MaxThroughput: maxThroughput})
+		if err != nil {
+			return err
+		}
+		r.Header.Set(HeaderOfferAutoscale, string(b))
+		return apiVersion(AutoscaleVersion)(r)
+	}
+}
+
+// PopulateQuotaInfo returns the quota, usage and index transformation progress
+// of a collection with its read
+func PopulateQuotaInfo() CallOption {
+	return func(r *Request) error {
+		r.Header.Set(HeaderPopulateQuotaInfo, "true")
+		return nil
+	}
+}
+
+// mediaHeaders sets the headers of a media upload
+func mediaHeaders(slug, contentType string) CallOption {
+	return func(r *Request) error {
+		r.Header.Set(HeaderSlug, slug)
+		r.Header.Set(HeaderContentType, contentType)
+		return nil
+	}
+}
+
+// withContext binds the request to ctx, used by callers that can't pass the
+// context down to the `*WithContext` methods (e.g: iterator sources)
+func withContext(ctx context.Context) CallOption {
+	return func(r *Request) error {
+		r.Request = r.Request.WithContext(ctx)
+		return nil
+	}
+}
+
+// withoutRetries disables the retries of throttled requests, used by callers
+// that handle them (e.g: the bulk executor)
+func withoutRetries() CallOption {
+	return func(r *Request) error {
+		r.noRetry = true
+		return nil
+	}
+}
+
+// partitionKeyValues sets the partition key header to the values of all the
+// partition key paths
+func partitionKeyValues(values []interface{}) CallOption {
+	pk, err := Serialization.Marshal(values)
+	header := []string{string(pk)}
+	return func(r *Request) error {
+		if err != nil {
+			return err
+		}
+		r.Header[HeaderPartitionKey] = header
+		return nil
+	}
+}
+
+// apiVersion sends the request with the given API version
+func apiVersion(version string) CallOption {
+	return func(r *Request) error {
+		r.version = version
+		r.Header.Set(HeaderVersion, version)
+		return nil
+	}
+}
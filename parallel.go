@@ -0,0 +1,298 @@
+package documentdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrOrderedQuery is returned by ParallelIterator for queries whose results need to
+// be merged across partitions (ORDER BY, aggregates, DISTINCT, TOP, ...), they're
+// run by QueryDocumentsCrossPartition
+var ErrOrderedQuery = errors.New("documentdb: query can't run in parallel, use QueryDocumentsCrossPartition")
+
+// ParallelOptions configures a ParallelIterator
+type ParallelOptions struct {
+	// MaxDegreeOfParallelism is the max number of partition key ranges queried
+	// at the same time, all of them if <= 0
+	MaxDegreeOfParallelism int
+	// MaxBufferedItemCount is the number of documents fetched ahead of Next,
+	// it's exceeded by at most one page per range. No limit if <= 0
+	MaxBufferedItemCount int
+	// Continuation resumes the iterator from the value of Continuation
+	Continuation string
+}
+
+// ParallelIterator queries the partition key ranges of a collection concurrently
+// and returns the pages in the order they arrive
+type ParallelIterator struct {
+	db      *DocumentDB
+	coll    string
+	query   *Query
+	docs    interface{}
+	options ParallelOptions
+	opts    []CallOption
+
+	started  bool
+	ranges   []*rangeQuery
+	tokens   map[*rangeQuery]*rangeToken
+	pages    chan *parallelPage
+	ctx      context.Context
+	cancel   context.CancelFunc
+	response *Response
+	err      error
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	buffered int
+	closed   bool
+}
+
+// parallelPage is a page of results of a range
+type parallelPage struct {
+	query        *rangeQuery
+	items        []json.RawMessage
+	continuation string
+	response     *Response
+	err          error
+}
+
+// rangeToken is the state of a range in a composite continuation
+type rangeToken struct {
+	ID    string `json:"id"`
+	Min   string `json:"min"`
+	Max   string `json:"max"`
+	Token string `json:"token,omitempty"`
+}
+
+// NewParallelIterator creates iterator that queries the partition key ranges of coll
+// concurrently, each call to Next decodes a page of documents into docs.
+// Close must be called if the iterator is not read until its end
+func NewParallelIterator(db *DocumentDB, coll string, query *Query, docs interface{}, options ParallelOptions, opts ...CallOption) *ParallelIterator {
+	it := &ParallelIterator{
+		db:      db,
+		coll:    coll,
+		query:   query,
+		docs:    docs,
+		options: options,
+		opts:    opts,
+		tokens:  make(map[*rangeQuery]*rangeToken),
+	}
+	it.cond = sync.NewCond(&it.mu)
+	it.ctx, it.cancel = context.WithCancel(context.Background())
+	return it
+}
+
+// Response returns *Response object of the last page
+func (it *ParallelIterator) Response() *Response {
+	return it.response
+}
+
+// Error returns error from last call
+func (it *ParallelIterator) Error() error {
+	return it.err
+}
+
+// Next decodes the next page into docs, it returns false when all the ranges
+// are read or on error
+func (it *ParallelIterator) Next() bool {
+	return it.NextWithContext(context.Background())
+}
+
+// NextWithContext is like Next, ctx bounds the wait for the next page
+func (it *ParallelIterator) NextWithContext(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		it.started = true
+		if it.err = it.start(ctx); it.err != nil {
+			it.Close()
+			return false
+		}
+	}
+	for {
+		var (
+			page *parallelPage
+			ok   bool
+		)
+		select {
+		case page, ok = <-it.pages:
+		case <-ctx.Done():
+			it.err = ctx.Err()
+			it.Close()
+			return false
+		}
+		if !ok {
+			it.Close()
+			return false
+		}
+		it.release(len(page.items))
+		if page.err != nil {
+			it.err = page.err
+			it.Close()
+			return false
+		}
+		it.tokens[page.query].Token = page.continuation
+		if page.continuation == "" {
+			delete(it.tokens, page.query)
+		}
+		if len(page.items) == 0 {
+			continue
+		}
+		it.response = page.response
+		data := []byte{'['}
+		data = append(data, bytes.Join(toBytes(page.items), []byte{','})...)
+		data = append(data, ']')
+		if it.err = Serialization.Unmarshal(data, it.docs); it.err != nil {
+			it.Close()
+			return false
+		}
+		return true
+	}
+}
+
+// Continuation returns the composite continuation of the pages returned by Next,
+// it's empty when all the ranges are read
+func (it *ParallelIterator) Continuation() string {
+	if !it.started {
+		return it.options.Continuation
+	}
+	if len(it.tokens) == 0 {
+		return ""
+	}
+	tokens := make([]*rangeToken, 0, len(it.tokens))
+	for _, r := range it.ranges {
+		if token, ok := it.tokens[r]; ok {
+			tokens = append(tokens, token)
+		}
+	}
+	b, _ := Serialization.Marshal(tokens)
+	return string(b)
+}
+
+// Close stops the queries of the ranges
+func (it *ParallelIterator) Close() {
+	it.cancel()
+	it.mu.Lock()
+	it.closed = true
+	it.mu.Unlock()
+	it.cond.Broadcast()
+}
+
+// start creates the queries of the ranges, from the continuation or from the
+// query plan, and starts them
+func (it *ParallelIterator) start(ctx context.Context) error {
+	if it.options.Continuation != "" {
+		var tokens []*rangeToken
+		if err := Serialization.Unmarshal([]byte(it.options.Continuation), &tokens); err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			it.add(&rangeQuery{query: it.query, rangeID: token.ID, min: token.Min, max: token.Max, continuation: token.Token})
+		}
+	} else {
+		q, err := it.db.prepareCrossPartitionQuery(ctx, it.coll, it.query, it.opts)
+		if err != nil {
+			return err
+		}
+		if !q.plan.QueryInfo.unordered() {
+			return ErrOrderedQuery
+		}
+		for _, r := range q.ranges {
+			it.add(r)
+		}
+	}
+
+	workers := it.options.MaxDegreeOfParallelism
+	if workers <= 0 || workers > len(it.ranges) {
+		workers = len(it.ranges)
+	}
+	it.pages = make(chan *parallelPage, len(it.ranges))
+	queue := make(chan *rangeQuery, len(it.ranges))
+	for _, r := range it.ranges {
+		queue <- r
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for r := range queue {
+				if !it.read(r) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(it.pages)
+	}()
+	return nil
+}
+
+func (it *ParallelIterator) add(r *rangeQuery) {
+	// The requests outlive the context of the first Next, and the charges
+	// are reported by the response of each page
+	r.db, r.ctx, r.coll, r.opts, r.charge = it.db, it.ctx, it.coll, it.opts, nil
+	it.ranges = append(it.ranges, r)
+	it.tokens[r] = &rangeToken{ID: r.rangeID, Min: r.min, Max: r.max, Token: r.continuation}
+}
+
+// read sends the pages of the range until it's done, it returns false if the
+// iterator is closed or the range failed
+func (it *ParallelIterator) read(r *rangeQuery) bool {
+	for {
+		if !it.reserve() {
+			return false
+		}
+		res, err := r.fetch()
+		page := &parallelPage{query: r, items: r.items, continuation: r.continuation, response: res, err: err}
+		r.items = nil
+		it.mu.Lock()
+		it.buffered += len(page.items)
+		it.mu.Unlock()
+		select {
+		case it.pages <- page:
+		case <-it.ctx.Done():
+			return false
+		}
+		if err != nil {
+			return false
+		}
+		if r.done {
+			return true
+		}
+	}
+}
+
+// reserve waits until the buffered documents are below the limit
+func (it *ParallelIterator) reserve() bool {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	for !it.closed && it.options.MaxBufferedItemCount > 0 && it.buffered >= it.options.MaxBufferedItemCount {
+		it.cond.Wait()
+	}
+	return !it.closed
+}
+
+// release marks n documents as returned by Next
+func (it *ParallelIterator) release(n int) {
+	it.mu.Lock()
+	it.buffered -= n
+	it.mu.Unlock()
+	it.cond.Broadcast()
+}
+
+func toBytes(items []json.RawMessage) [][]byte {
+	b := make([][]byte, len(items))
+	for i, item := range items {
+		b[i] = item
+	}
+	return b
+}
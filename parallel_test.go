@@ -0,0 +1,104 @@
+package documentdb
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parallelGateway() *fakeGateway {
+	return newFakeGateway(`{"queryInfo": {}, "queryRanges": [{"min": "", "max": "FF", "isMinInclusive": true}]}`, map[string][]string{
+		"0": {`1`, `4`, `7`, `10`},
+		"1": {`2`, `5`, `8`},
+		"2": {`3`, `6`, `9`, `11`, `12`},
+	})
+}
+
+// drain reads the iterator until its end
+func drain(it *ParallelIterator, docs *[]int) (all []int) {
+	for it.Next() {
+		all = append(all, *docs...)
+	}
+	sort.Ints(all)
+	return
+}
+
+func TestParallelIterator(t *testing.T) {
+	assert := assert.New(t)
+	g := parallelGateway()
+	defer g.Close()
+
+	var docs []int
+	it := NewParallelIterator(g.DB(), "dbs/db/colls/coll/", NewQuery("SELECT VALUE c.n FROM c"), &docs, ParallelOptions{MaxDegreeOfParallelism: 2})
+	assert.Equal([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, drain(it, &docs))
+	assert.Nil(it.Error())
+	assert.Equal("", it.Continuation())
+	assert.Equal(1.0, it.Response().RequestCharge())
+}
+
+func TestParallelIteratorDegreeOfParallelism(t *testing.T) {
+	assert := assert.New(t)
+	g := parallelGateway()
+	defer g.Close()
+	g.delay = 5 * time.Millisecond
+
+	var docs []int
+	it := NewParallelIterator(g.DB(), "dbs/db/colls/coll/", NewQuery("SELECT VALUE c.n FROM c"), &docs, ParallelOptions{MaxDegreeOfParallelism: 2})
+	assert.Len(drain(it, &docs), 12)
+	assert.True(g.maxInflight <= 2, "Should not query more than 2 ranges at the same time")
+}
+
+func TestParallelIteratorBufferedItems(t *testing.T) {
+	assert := assert.New(t)
+	g := parallelGateway()
+	defer g.Close()
+
+	var docs []int
+	it := NewParallelIterator(g.DB(), "dbs/db/colls/coll/", NewQuery("SELECT VALUE c.n FROM c"), &docs, ParallelOptions{MaxBufferedItemCount: 1})
+	defer it.Close()
+	assert.True(it.Next())
+	time.Sleep(20 * time.Millisecond)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// The page returned by Next, and one page per range at most: the buffer
+	// is full until Next is called again
+	assert.True(len(g.queries) <= 4, "Should stop fetching when the buffer is full")
+}
+
+func TestParallelIteratorContinuation(t *testing.T) {
+	assert := assert.New(t)
+	g := parallelGateway()
+	defer g.Close()
+
+	var (
+		docs  []int
+		first []int
+	)
+	query := NewQuery("SELECT VALUE c.n FROM c")
+	it := NewParallelIterator(g.DB(), "dbs/db/colls/coll/", query, &docs, ParallelOptions{MaxDegreeOfParallelism: 1})
+	for i := 0; i < 3 && it.Next(); i++ {
+		first = append(first, docs...)
+	}
+	continuation := it.Continuation()
+	it.Close()
+	assert.NotEqual("", continuation)
+
+	it = NewParallelIterator(g.DB(), "dbs/db/colls/coll/", query, &docs, ParallelOptions{Continuation: continuation})
+	assert.Equal(continuation, it.Continuation())
+	rest := drain(it, &docs)
+	all := append(first, rest...)
+	sort.Ints(all)
+	assert.Equal([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, all)
+}
+
+func TestParallelIteratorOrderedQuery(t *testing.T) {
+	g := newFakeGateway(`{"queryInfo": {"orderBy": ["Ascending"]}}`, nil)
+	defer g.Close()
+
+	var docs []int
+	it := NewParallelIterator(g.DB(), "dbs/db/colls/coll/", NewQuery("SELECT VALUE c.n FROM c ORDER BY c.n"), &docs, ParallelOptions{})
+	assert.False(t, it.Next())
+	assert.Equal(t, ErrOrderedQuery, it.Error())
+}
@@ -1,8 +1,16 @@
 package documentdb
 
+import (
+	"fmt"
+	"strings"
+)
+
+// Parameter of a query, Value is any value serializable to JSON (numbers,
+// booleans, nil, slices, maps and structs) and is encoded by the active
+// SerializationDriver with the query
 type Parameter struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
 }
 
 type P = Parameter
@@ -15,3 +23,63 @@ type Query struct {
 func NewQuery(query string, parameters ...Parameter) *Query {
 	return &Query{query, parameters}
 }
+
+// Validate checks that every parameter referenced in the query text
+// (e.g: @name) is bound, parameters in string literals and comments are
+// ignored. A nil query is valid
+func (q *Query) Validate() error {
+	if q == nil {
+		return nil
+	}
+	bound := make(map[string]bool, len(q.Parameters))
+	for _, p := range q.Parameters {
+		bound[p.Name] = true
+	}
+	for _, name := range parameterNames(q.Query) {
+		if !bound[name] {
+			return fmt.Errorf("documentdb: query parameter %s is not bound", name)
+		}
+	}
+	return nil
+}
+
+// parameterNames returns the names of the parameters referenced in the query text
+func parameterNames(text string) (names []string) {
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\'' || c == '"':
+			// Skip string literal
+			for i++; i < len(text) && text[i] != c; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case c == '-' && strings.HasPrefix(text[i:], "--"):
+			// Skip comment to the end of the line
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(text[i:], "/*"):
+			// Skip block comment
+			if end := strings.Index(text[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(text)
+			}
+		case c == '@':
+			j := i + 1
+			for j < len(text) && isIdentifier(text[j], j > i+1) {
+				j++
+			}
+			if j > i+1 {
+				names = append(names, text[i:j])
+			}
+			i = j - 1
+		}
+	}
+	return
+}
+
+func isIdentifier(c byte, digits bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || digits && '0' <= c && c <= '9'
+}
@@ -0,0 +1,109 @@
+package documentdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryParameterValues(t *testing.T) {
+	assert := assert.New(t)
+	q := NewQuery("SELECT * FROM c WHERE c.age > @age AND c.active = @active AND c.deletedAt = @deleted AND ARRAY_CONTAINS(@ids, c.id) AND c.address = @address",
+		P{"@age", 30},
+		P{"@active", true},
+		P{"@deleted", nil},
+		P{"@ids", []string{"1", "2"}},
+		P{"@address", map[string]interface{}{"city": "Tel Aviv"}},
+	)
+	assert.Nil(q.Validate())
+	b, err := json.Marshal(q.Parameters)
+	assert.Nil(err)
+	assert.Equal(`[{"name":"@age","value":30},{"name":"@active","value":true},{"name":"@deleted","value":null},{"name":"@ids","value":["1","2"]},{"name":"@address","value":{"city":"Tel Aviv"}}]`, string(b))
+}
+
+func TestQueryValidate(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"SELECT * FROM c", ""},
+		{"SELECT * FROM c WHERE c.name = @name", ""},
+		{"SELECT * FROM c WHERE c.name = @name AND c.age = @age", "documentdb: query parameter @age is not bound"},
+		{"SELECT * FROM c WHERE c.email = 'john@example.com' AND c.name = @name", ""},
+		{`SELECT * FROM c WHERE c.note = "it\"s @home" AND c.name = @name`, ""},
+		{"SELECT * FROM c WHERE c.name = @name2", "documentdb: query parameter @name2 is not bound"},
+		{"SELECT * FROM c WHERE c.name = @name_0", "documentdb: query parameter @name_0 is not bound"},
+		{"SELECT * FROM c -- AND c.age = @age\nWHERE c.name = @name", ""},
+		{"SELECT * FROM c /* c.age = @age */ WHERE c.name = @name", ""},
+		{"SELECT * FROM c /* @age */ WHERE c.age = @age", "documentdb: query parameter @age is not bound"},
+		{"SELECT * FROM c WHERE c.email = 'x -- @age' AND c.name = @name", ""},
+		{"SELECT * FROM c WHERE c.n = 1 - -@age", "documentdb: query parameter @age is not bound"},
+	}
+	for _, test := range tests {
+		err := NewQuery(test.query, P{"@name", "john"}).Validate()
+		if test.err == "" {
+			assert.Nil(err, test.query)
+		} else if assert.NotNil(err, test.query) {
+			assert.Equal(test.err, err.Error())
+		}
+	}
+}
+
+func TestQueryValidateNil(t *testing.T) {
+	var q *Query
+	assert.Nil(t, q.Validate())
+}
+
+func TestQueryUnboundParameter(t *testing.T) {
+	var calls int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+
+	_, err := client.Query("dbs/db/colls/coll/docs", NewQuery("SELECT * FROM c WHERE c.age > @age"), nil)
+	assert.EqualError(t, err, "documentdb: query parameter @age is not bound")
+	assert.Equal(t, 0, calls, "Should not send the query")
+}
+
+type recordingEncoder struct {
+	values *[]interface{}
+	*json.Encoder
+}
+
+func (e recordingEncoder) Encode(v interface{}) error {
+	*e.values = append(*e.values, v)
+	return e.Encoder.Encode(v)
+}
+
+func TestQuerySerializationDriver(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		encoded []interface{}
+		body    string
+	)
+	defer func(d SerializationDriver) { Serialization = d }(Serialization)
+	Serialization.EncoderFactory = func(b *bytes.Buffer) JSONEncoder {
+		return recordingEncoder{&encoded, json.NewEncoder(b)}
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		buf.ReadFrom(r.Body)
+		body = buf.String()
+		w.Write([]byte(`{"Documents": []}`))
+	}))
+	defer s.Close()
+	client := &Client{Url: s.URL, Config: NewConfig(&Key{Key: "YXJpZWwNCg=="})}
+
+	q := NewQuery("SELECT * FROM c WHERE c.age > @age", P{"@age", 30.5})
+	_, err := client.Query("dbs/db/colls/coll/docs", q, nil)
+	assert.Nil(err)
+	assert.Equal([]interface{}{q}, encoded)
+	assert.JSONEq(`{"query": "SELECT * FROM c WHERE c.age > @age", "parameters": [{"name": "@age", "value": 30.5}]}`, body)
+}
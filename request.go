@@ -3,6 +3,8 @@ package documentdb
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -33,16 +35,84 @@ const (
 	HeaderAIM                 = "A-IM"
 	HeaderPartitionKeyRangeID = "x-ms-documentdb-partitionkeyrangeid"
 	HeaderUserAgent           = "User-Agent"
+	HeaderRetryAfter          = "x-ms-retry-after-ms"
+	HeaderSubStatus           = "x-ms-substatus"
+	HeaderItemCount           = "x-ms-item-count"
+	HeaderEtag                = "Etag"
+	HeaderLSN                 = "lsn"
+	HeaderResourceQuota       = "x-ms-resource-quota"
+	HeaderResourceUsage       = "x-ms-resource-usage"
+	HeaderIndexTransformation = "x-ms-documentdb-collection-index-transformation-progress"
+	HeaderServiceVersion      = "x-ms-serviceversion"
+	HeaderExpirySeconds       = "x-ms-documentdb-expiry-seconds"
+	HeaderPreTriggerInclude   = "x-ms-documentdb-pre-trigger-include"
+	HeaderPostTriggerInclude  = "x-ms-documentdb-post-trigger-include"
+	HeaderSlug                = "Slug"
+	HeaderOfferThroughput     = "x-ms-offer-throughput"
+	HeaderOfferAutoscale      = "x-ms-cosmos-offer-autopilot-settings"
+	HeaderPopulateQuotaInfo   = "x-ms-documentdb-populatequotainfo"
+	HeaderMaxMediaStorage     = "x-ms-max-media-storage-usage-mb"
+	HeaderMediaStorage        = "x-ms-media-storage-usage-mb"
+
+	// Query plan headers
+	HeaderIsQueryPlanRequest     = "x-ms-cosmos-is-query-plan-request"
+	HeaderSupportedQueryFeatures = "x-ms-cosmos-supported-query-features"
+	HeaderQueryVersion           = "x-ms-cosmos-query-version"
 
 	SupportedVersion = "2017-02-22"
+	// AutoscaleVersion is the min API version of the autoscale throughput, it's
+	// used by the offer requests and the creations with AutoscaleMaxThroughput
+	AutoscaleVersion = "2018-12-31"
 
 	ServicePrincipalRefreshTimeout = 10 * time.Second
 )
 
+// Sentinel errors, a *RequestError matches them with errors.Is
+// according to its status code.
+var (
+	ErrNotFound           = errors.New("documentdb: resource not found")
+	ErrConflict           = errors.New("documentdb: resource already exists")
+	ErrPreconditionFailed = errors.New("documentdb: precondition failed")
+	ErrThrottled          = errors.New("documentdb: request rate is too large")
+	ErrGone               = errors.New("documentdb: resource is gone")
+	ErrNotModified        = errors.New("documentdb: resource not modified")
+)
+
+var statusErrors = map[int]error{
+	http.StatusNotFound:           ErrNotFound,
+	http.StatusConflict:           ErrConflict,
+	http.StatusPreconditionFailed: ErrPreconditionFailed,
+	http.StatusTooManyRequests:    ErrThrottled,
+	http.StatusGone:               ErrGone,
+	http.StatusNotModified:        ErrNotModified,
+}
+
 // Request Error
 type RequestError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+
+	// Fields below are filled from the response status and headers
+	StatusCode    int           `json:"-"`
+	SubStatus     int           `json:"-"`
+	ActivityID    string        `json:"-"`
+	RequestCharge float64       `json:"-"`
+	RetryAfter    time.Duration `json:"-"`
+	Etag          string        `json:"-"`
+}
+
+// newRequestError creates *RequestError from a failed response
+func newRequestError(resp *http.Response) *RequestError {
+	err := &RequestError{
+		StatusCode: resp.StatusCode,
+		ActivityID: resp.Header.Get(HeaderActivityID),
+		RetryAfter: retryAfter(resp.Header),
+		Etag:       resp.Header.Get(HeaderEtag),
+	}
+	err.SubStatus, _ = strconv.Atoi(resp.Header.Get(HeaderSubStatus))
+	err.RequestCharge, _ = strconv.ParseFloat(resp.Header.Get(HeaderRequestCharge), 64)
+	readJson(resp.Body, &err)
+	return err
 }
 
 // Implement Error function
@@ -50,24 +120,47 @@ func (e RequestError) Error() string {
 	return fmt.Sprintf("%v, %v", e.Code, e.Message)
 }
 
+// Is reports whether the error matches one of the sentinel errors, e.g:
+// errors.Is(err, documentdb.ErrNotFound)
+func (e RequestError) Is(target error) bool {
+	return e.StatusCode != 0 && statusErrors[e.StatusCode] == target
+}
+
 // Resource Request
 type Request struct {
 	rId, rType string
+	link       string
+	// noRetry sends the throttled requests only once, whatever the RetryPolicy
+	noRetry bool
+	// rerouted is set once a write forbidden by its region was sent again
+	rerouted bool
+	// version overrides the SupportedVersion API version
+	version string
 	*http.Request
 }
 
 // Return new resource request with type and id
 func ResourceRequest(link string, req *http.Request) *Request {
 	rId, rType := parse(link)
-	return &Request{rId, rType, req}
+	return &Request{rId: rId, rType: rType, link: link, Request: req}
+}
+
+// isRead reports whether the request only reads resources
+func (req *Request) isRead() bool {
+	return req.Method == http.MethodGet || req.Header.Get(HeaderIsQuery) == "true"
 }
 
 // Add 3 default headers to *Request
 // "x-ms-date", "x-ms-version", "authorization"
+// Calling it again on the same request re-signs it with a fresh date
 func (req *Request) DefaultHeaders(config *Config, userAgent string) (err error) {
-	req.Header.Add(HeaderXDate, formatDate(time.Now()))
-	req.Header.Add(HeaderVersion, SupportedVersion)
-	req.Header.Add(HeaderUserAgent, userAgent)
+	req.Header.Set(HeaderXDate, formatDate(time.Now()))
+	if req.version != "" {
+		req.Header.Set(HeaderVersion, req.version)
+	} else {
+		req.Header.Set(HeaderVersion, SupportedVersion)
+	}
+	req.Header.Set(HeaderUserAgent, userAgent)
 
 	// Authentication via master key
 	if config.MasterKey != nil && config.MasterKey.Key != "" {
@@ -91,7 +184,7 @@ func (req *Request) DefaultHeaders(config *Config, userAgent string) (err error)
 
 		buffers.Put(b)
 
-		req.Header.Add(HeaderAuth, url.QueryEscape("type=master&ver=1.0&sig="+sign))
+		req.Header.Set(HeaderAuth, url.QueryEscape("type=master&ver=1.0&sig="+sign))
 	} else if config.ServicePrincipal != nil {
 		ctx, cancel := context.WithTimeout(req.Context(), ServicePrincipalRefreshTimeout)
 		defer cancel()
@@ -100,12 +193,47 @@ func (req *Request) DefaultHeaders(config *Config, userAgent string) (err error)
 			return err
 		}
 		token := config.ServicePrincipal.OAuthToken()
-		req.Header.Add(HeaderAuth, url.QueryEscape("type=aad&ver=1.0&sig="+token))
+		req.Header.Set(HeaderAuth, url.QueryEscape("type=aad&ver=1.0&sig="+token))
+	} else if config.ResourceTokens != nil {
+		token, ok := resourceToken(config.ResourceTokens, req.link)
+		if !ok {
+			return fmt.Errorf("no resource token for %q", req.link)
+		}
+		req.Header.Set(HeaderAuth, url.QueryEscape(token))
 	}
 
 	return
 }
 
+// resourceToken returns the token of link, or of its closest parent resource
+func resourceToken(tokens map[string]string, link string) (string, bool) {
+	link = strings.Trim(link, "/")
+	for link != "" {
+		if token, ok := tokens[link]; ok {
+			return token, true
+		}
+		i := strings.LastIndexByte(link, '/')
+		if i == -1 {
+			break
+		}
+		link = link[:i]
+	}
+	return "", false
+}
+
+// rewind prepares the request to be sent again, the body is replayed from
+// the start and the date and authorization headers are signed again
+func (req *Request) rewind(config *Config, userAgent string) error {
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		req.Body = body
+	}
+	return req.DefaultHeaders(config, userAgent)
+}
+
 // Add headers for query request
 func (req *Request) QueryHeaders(len int) {
 	req.Header.Add(HeaderContentType, "application/query+json")
@@ -114,6 +242,10 @@ func (req *Request) QueryHeaders(len int) {
 }
 
 func parse(id string) (rId, rType string) {
+	// The database account is addressed by the root link
+	if strings.Trim(id, "/") == "" {
+		return "", ""
+	}
 	if strings.HasPrefix(id, "/") == false {
 		id = "/" + id
 	}
@@ -124,6 +256,15 @@ func parse(id string) (rId, rType string) {
 	parts := strings.Split(id, "/")
 	l := len(parts)
 
+	// Media is signed with the id of its attachment
+	if l == 4 && parts[1] == "media" {
+		return strings.ToLower(attachmentID(parts[2])), "media"
+	}
+	// Offers are only addressed by their rid
+	if l == 4 && parts[1] == "offers" {
+		return strings.ToLower(parts[2]), "offers"
+	}
+
 	if l%2 == 0 {
 		rType = parts[l-3]
 	} else {
@@ -153,6 +294,20 @@ func parse(id string) (rId, rType string) {
 	return
 }
 
+// mediaEncoding is the base64 encoding of the media ids, "/" is replaced by "-"
+var mediaEncoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+-")
+
+// attachmentID returns the id of the attachment that owns the media,
+// i.e: the media id without its storage index
+func attachmentID(mediaID string) string {
+	const ridLength = 20
+	b, err := mediaEncoding.DecodeString(mediaID)
+	if err != nil || len(b) <= ridLength {
+		return mediaID
+	}
+	return mediaEncoding.EncodeToString(b[:ridLength])
+}
+
 func formatDate(t time.Time) string {
 	t = t.UTC()
 	return t.Format("Mon, 02 Jan 2006 15:04:05 GMT")
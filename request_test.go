@@ -3,7 +3,9 @@ package documentdb
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,7 +25,7 @@ func TestResourceRequest(t *testing.T) {
 	assert := assert.New(t)
 	req := ResourceRequest("/dbs/b5NCAA==/", &http.Request{})
 	assert.Equal(req.rType, "dbs")
-	assert.Equal(req.rId, "b5NCAA==")
+	assert.Equal(req.rId, "b5ncaa==")
 }
 
 func TestDefaultHeaders(t *testing.T) {
@@ -79,3 +81,72 @@ func TestPartitionKeyAsString(t *testing.T) {
 	assert := assert.New(t)
 	assert.Equal([]string{"[\"1\"]"}, req.Header[HeaderPartitionKey])
 }
+
+func TestRequestErrorIs(t *testing.T) {
+	assert := assert.New(t)
+	expectations := []struct {
+		status int
+		target error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusConflict, ErrConflict},
+		{http.StatusPreconditionFailed, ErrPreconditionFailed},
+		{http.StatusTooManyRequests, ErrThrottled},
+		{http.StatusGone, ErrGone},
+	}
+	for _, e := range expectations {
+		err := &RequestError{StatusCode: e.status}
+		assert.True(errors.Is(err, e.target), "status %d should match %v", e.status, e.target)
+		assert.False(errors.Is(&RequestError{StatusCode: http.StatusBadRequest}, e.target))
+	}
+	assert.False(errors.Is(&RequestError{}, ErrNotFound), "error without status should not match")
+}
+
+func TestDefaultHeadersResourceTokens(t *testing.T) {
+	assert := assert.New(t)
+	config := NewConfigWithResourceTokens(map[string]string{
+		"/dbs/db/colls/coll/":          "type=resource&ver=1&sig=coll",
+		"dbs/db/colls/coll/docs/doc-1": "type=resource&ver=1&sig=doc",
+	})
+
+	expectations := []struct {
+		link  string
+		token string
+	}{
+		{"dbs/db/colls/coll/docs/doc-1", "type=resource&ver=1&sig=doc"},
+		{"dbs/db/colls/coll/docs/doc-2", "type=resource&ver=1&sig=coll"},
+		{"dbs/db/colls/coll/docs/", "type=resource&ver=1&sig=coll"},
+	}
+	for _, e := range expectations {
+		r, _ := http.NewRequest("GET", "link", &bytes.Buffer{})
+		req := ResourceRequest(e.link, r)
+		assert.Nil(req.DefaultHeaders(config, ""))
+		assert.Equal(url.QueryEscape(e.token), req.Header.Get(HeaderAuth), e.link)
+	}
+
+	r, _ := http.NewRequest("GET", "link", &bytes.Buffer{})
+	req := ResourceRequest("dbs/db/colls/other/docs/", r)
+	assert.EqualError(req.DefaultHeaders(config, ""), `no resource token for "dbs/db/colls/other/docs/"`)
+}
+
+func TestTriggerHeaders(t *testing.T) {
+	r, _ := http.NewRequest("POST", "link", &bytes.Buffer{})
+	req := ResourceRequest("/dbs/db/colls/coll/docs/", r)
+
+	PreTrigger("validate", "stamp")(req)
+	PostTrigger("audit")(req)
+
+	assert := assert.New(t)
+	assert.Equal("validate,stamp", req.Header.Get(HeaderPreTriggerInclude))
+	assert.Equal("audit", req.Header.Get(HeaderPostTriggerInclude))
+}
+
+func TestMediaRequest(t *testing.T) {
+	assert := assert.New(t)
+	req := ResourceRequest("/media/Sl8fAOZ7RAcBAAAAAAAAAJ8knhoBAgM=", &http.Request{})
+	assert.Equal("media", req.rType)
+	assert.Equal("sl8faoz7racbaaaaaaaaaj8knho=", req.rId)
+
+	req = ResourceRequest("/media/b5NCAJk5AQA=", &http.Request{})
+	assert.Equal("b5ncajk5aqa=", req.rId)
+}
@@ -3,10 +3,13 @@ package documentdb
 import (
 	"math"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type Response struct {
-	Header http.Header
+	Header     http.Header
+	StatusCode int
 }
 
 // Continuation returns continuation token for paged request.
@@ -15,6 +18,79 @@ func (r *Response) Continuation() string {
 	return r.Header.Get(HeaderContinuation)
 }
 
+// RequestCharge returns the number of request units (RU) consumed by the operation.
+func (r *Response) RequestCharge() float64 {
+	charge, _ := strconv.ParseFloat(r.Header.Get(HeaderRequestCharge), 64)
+	return charge
+}
+
+// SessionToken returns the session token of the request, used for session consistency.
+func (r *Response) SessionToken() string {
+	return r.Header.Get(HeaderSessionToken)
+}
+
+// ActivityID returns the unique identifier of the operation, useful for troubleshooting.
+func (r *Response) ActivityID() string {
+	return r.Header.Get(HeaderActivityID)
+}
+
+// ItemCount returns the number of items returned by a query or read-feed operation.
+func (r *Response) ItemCount() int {
+	count, _ := strconv.Atoi(r.Header.Get(HeaderItemCount))
+	return count
+}
+
+// Etag returns the etag of the resource retrieved.
+func (r *Response) Etag() string {
+	return r.Header.Get(HeaderEtag)
+}
+
+// LSN returns the logical sequence number of the resource.
+func (r *Response) LSN() int64 {
+	lsn, _ := strconv.ParseInt(r.Header.Get(HeaderLSN), 10, 64)
+	return lsn
+}
+
+// ResourceQuota returns the allotted quota for the resource type, e.g: {"collectionSize": 10485760, ...}
+func (r *Response) ResourceQuota() map[string]int64 {
+	return parseQuota(r.Header.Get(HeaderResourceQuota))
+}
+
+// ResourceUsage returns the current usage count of the resource type, e.g: {"documentsCount": 12, ...}
+func (r *Response) ResourceUsage() map[string]int64 {
+	return parseQuota(r.Header.Get(HeaderResourceUsage))
+}
+
+// IndexTransformationProgress returns the progress (in percent) of the index
+// transformation of a collection, ok is false if the header is missing.
+func (r *Response) IndexTransformationProgress() (progress int, ok bool) {
+	progress, err := strconv.Atoi(r.Header.Get(HeaderIndexTransformation))
+	return progress, err == nil
+}
+
+// ServiceVersion returns the version of the service that handled the request.
+func (r *Response) ServiceVersion() string {
+	return r.Header.Get(HeaderServiceVersion)
+}
+
+// parseQuota parses the quota and usage headers, formatted as "key1=value1;key2=value2;"
+func parseQuota(header string) map[string]int64 {
+	if header == "" {
+		return nil
+	}
+	quota := make(map[string]int64)
+	for _, kv := range strings.Split(header, ";") {
+		i := strings.IndexByte(kv, '=')
+		if i == -1 {
+			continue
+		}
+		if v, err := strconv.ParseInt(kv[i+1:], 10, 64); err == nil {
+			quota[kv[:i]] = v
+		}
+	}
+	return quota
+}
+
 type statusCodeValidatorFunc func(statusCode int) bool
 
 func expectStatusCode(expected int) statusCodeValidatorFunc {
@@ -1,6 +1,7 @@
 package documentdb
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -44,3 +45,38 @@ func TestExpectStatusCodeXX(t *testing.T) {
 	}
 
 }
+
+func TestResponseHeaders(t *testing.T) {
+	assert := assert.New(t)
+	header := http.Header{}
+	header.Set(HeaderRequestCharge, "12.38")
+	header.Set(HeaderSessionToken, "0:1#12")
+	header.Set(HeaderActivityID, "activity")
+	header.Set(HeaderItemCount, "5")
+	header.Set(HeaderEtag, `"00000a00-0000-0000-0000-000000000000"`)
+	header.Set(HeaderLSN, "42")
+	header.Set(HeaderResourceQuota, "documentSize=10240;documentsCount=-1;collectionSize=10240;")
+	header.Set(HeaderResourceUsage, "documentSize=1;documentsCount=12;collectionSize=2;")
+	header.Set(HeaderIndexTransformation, "87")
+	header.Set(HeaderServiceVersion, "version=2.14.0.0")
+	r := &Response{Header: header, StatusCode: http.StatusOK}
+
+	assert.Equal(12.38, r.RequestCharge())
+	assert.Equal("0:1#12", r.SessionToken())
+	assert.Equal("activity", r.ActivityID())
+	assert.Equal(5, r.ItemCount())
+	assert.Equal(`"00000a00-0000-0000-0000-000000000000"`, r.Etag())
+	assert.Equal(int64(42), r.LSN())
+	assert.Equal(map[string]int64{"documentSize": 10240, "documentsCount": -1, "collectionSize": 10240}, r.ResourceQuota())
+	assert.Equal(map[string]int64{"documentSize": 1, "documentsCount": 12, "collectionSize": 2}, r.ResourceUsage())
+	progress, ok := r.IndexTransformationProgress()
+	assert.True(ok)
+	assert.Equal(87, progress)
+	assert.Equal("version=2.14.0.0", r.ServiceVersion())
+
+	empty := &Response{Header: http.Header{}}
+	assert.Equal(0.0, empty.RequestCharge())
+	assert.Nil(empty.ResourceQuota())
+	_, ok = empty.IndexTransformationProgress()
+	assert.False(ok)
+}
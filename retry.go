@@ -0,0 +1,66 @@
+package documentdb
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how requests that were throttled by the service
+// (429, "Request rate is too large") are retried
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts per request, including the
+	// first one. A value lower than 2 disables retries
+	MaxAttempts int
+	// MaxWait bounds the cumulative time spent waiting between attempts.
+	// Zero means no bound
+	MaxWait time.Duration
+	// Jitter is the max random duration added to every wait
+	Jitter time.Duration
+}
+
+// DefaultRetryPolicy holds the values used by the other sdks
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 10,
+	MaxWait:     30 * time.Second,
+	Jitter:      50 * time.Millisecond,
+}
+
+// backoff returns how long to wait before the given attempt, and whether
+// the request should be retried at all
+func (p *RetryPolicy) backoff(attempt int, waited time.Duration, resp *http.Response) (time.Duration, bool) {
+	if p == nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	wait := retryAfter(resp.Header)
+	if p.Jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+	if p.MaxWait > 0 && waited+wait > p.MaxWait {
+		return 0, false
+	}
+	return wait, true
+}
+
+// retryAfter parses the `x-ms-retry-after-ms` header
+func retryAfter(header http.Header) time.Duration {
+	ms, err := strconv.ParseFloat(header.Get(HeaderRetryAfter), 64)
+	if err != nil || ms < 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
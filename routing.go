@@ -0,0 +1,214 @@
+package documentdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Bounds of the effective partition keys
+const (
+	MinEffectivePartitionKey = ""
+	MaxEffectivePartitionKey = "FF"
+)
+
+// RoutingMap is the partition key ranges of a collection sorted by their min,
+// it's immutable
+type RoutingMap struct {
+	ranges []PartitionKeyRange
+	// etag is the continuation of the change feed of the ranges
+	etag string
+}
+
+// Ranges returns the partition key ranges sorted by their min
+func (m *RoutingMap) Ranges() []PartitionKeyRange {
+	return append([]PartitionKeyRange(nil), m.ranges...)
+}
+
+// Range returns the partition key range by id
+func (m *RoutingMap) Range(id string) (PartitionKeyRange, bool) {
+	for _, r := range m.ranges {
+		if r.PartitionKeyRangeID == id {
+			return r, true
+		}
+	}
+	return PartitionKeyRange{}, false
+}
+
+// RangeByEPK returns the partition key range that owns the effective partition key
+func (m *RoutingMap) RangeByEPK(epk string) (PartitionKeyRange, bool) {
+	// The first range whose max is greater than epk
+	i := sort.Search(len(m.ranges), func(i int) bool {
+		return m.ranges[i].MaxInclusive > epk
+	})
+	if i == len(m.ranges) || m.ranges[i].MinInclusive > epk {
+		return PartitionKeyRange{}, false
+	}
+	return m.ranges[i], true
+}
+
+// Overlapping returns the partition key ranges overlapping [min, max)
+func (m *RoutingMap) Overlapping(min, max string) []PartitionKeyRange {
+	var ranges []PartitionKeyRange
+	q := queryRange{Min: min, Max: max, IsMinInclusive: true}
+	for _, r := range m.ranges {
+		if q.overlaps(r) {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// complete reports whether the ranges cover all the effective partition keys
+func (m *RoutingMap) complete() bool {
+	last := MinEffectivePartitionKey
+	for _, r := range m.ranges {
+		if r.MinInclusive != last {
+			return false
+		}
+		last = r.MaxInclusive
+	}
+	return last == MaxEffectivePartitionKey
+}
+
+// merge returns the routing map updated by the changed ranges, the parents of
+// the new ranges are removed
+func (m *RoutingMap) merge(changed []PartitionKeyRange, etag string) *RoutingMap {
+	gone := make(map[string]bool)
+	for _, r := range changed {
+		for _, parent := range r.Parents {
+			gone[parent] = true
+		}
+	}
+	ranges := make(map[string]PartitionKeyRange)
+	if m != nil {
+		for _, r := range m.ranges {
+			ranges[r.PartitionKeyRangeID] = r
+		}
+	}
+	for _, r := range changed {
+		ranges[r.PartitionKeyRangeID] = r
+	}
+	merged := &RoutingMap{etag: etag}
+	for id, r := range ranges {
+		if !gone[id] {
+			merged.ranges = append(merged.ranges, r)
+		}
+	}
+	sort.Slice(merged.ranges, func(i, j int) bool {
+		return merged.ranges[i].MinInclusive < merged.ranges[j].MinInclusive
+	})
+	return merged
+}
+
+// ErrIncompleteRoutingMap is returned when the partition key ranges of a
+// collection don't cover all the effective partition keys
+var ErrIncompleteRoutingMap = errors.New("documentdb: incomplete partition key ranges")
+
+// PartitionKeyRangeCache caches the routing maps of collections. They're updated
+// incrementally with the change feed of the partition key ranges, that reports
+// the ranges created by splits and merges
+type PartitionKeyRangeCache struct {
+	db *DocumentDB
+
+	mu   sync.Mutex
+	maps map[string]*RoutingMap
+	// refreshes serialize the refreshes of each collection, without blocking
+	// the other collections
+	refreshes map[string]*sync.Mutex
+}
+
+// NewPartitionKeyRangeCache creates an empty cache
+func NewPartitionKeyRangeCache(db *DocumentDB) *PartitionKeyRangeCache {
+	return &PartitionKeyRangeCache{db: db, maps: make(map[string]*RoutingMap), refreshes: make(map[string]*sync.Mutex)}
+}
+
+// RoutingMap returns the cached routing map of the collection, it's read on first use
+func (c *PartitionKeyRangeCache) RoutingMap(ctx context.Context, coll string) (*RoutingMap, error) {
+	c.mu.Lock()
+	m, ok := c.maps[coll]
+	c.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+	return c.Refresh(ctx, coll)
+}
+
+// Refresh reads the changes of the partition key ranges since the last refresh,
+// e.g: after a request failed with ErrGone
+func (c *PartitionKeyRangeCache) Refresh(ctx context.Context, coll string) (*RoutingMap, error) {
+	c.mu.Lock()
+	refresh, ok := c.refreshes[coll]
+	if !ok {
+		refresh = &sync.Mutex{}
+		c.refreshes[coll] = refresh
+	}
+	c.mu.Unlock()
+	refresh.Lock()
+	defer refresh.Unlock()
+
+	c.mu.Lock()
+	m := c.maps[coll]
+	c.mu.Unlock()
+	m, err := c.read(ctx, coll, m)
+	if err == nil && !m.complete() {
+		// The changes were partial, read all the ranges again
+		m, err = c.read(ctx, coll, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !m.complete() {
+		return nil, ErrIncompleteRoutingMap
+	}
+	c.mu.Lock()
+	c.maps[coll] = m
+	c.mu.Unlock()
+	return m, nil
+}
+
+// Invalidate removes the routing map of the collection from the cache
+func (c *PartitionKeyRangeCache) Invalidate(coll string) {
+	c.mu.Lock()
+	delete(c.maps, coll)
+	c.mu.Unlock()
+}
+
+// read applies the changes of the ranges to m until there are no more, or the
+// etag doesn't advance. All the ranges are read if m is nil
+func (c *PartitionKeyRangeCache) read(ctx context.Context, coll string, m *RoutingMap) (*RoutingMap, error) {
+	etag := ""
+	if m != nil {
+		etag = m.etag
+	}
+	for {
+		var data queryPartitionKeyRangesRequest
+		opts := []CallOption{ChangeFeed()}
+		if etag != "" {
+			opts = append(opts, IfNoneMatch(etag))
+		}
+		res, err := c.db.client.ReadWithContext(ctx, coll+"pkranges/", &data, opts...)
+		if err != nil {
+			var reqErr *RequestError
+			if errors.As(err, &reqErr) && reqErr.StatusCode == http.StatusNotModified {
+				if m == nil {
+					m = &RoutingMap{etag: etag}
+				}
+				return m, nil
+			}
+			return nil, err
+		}
+		next := res.Etag()
+		if next == "" {
+			// The gateway doesn't support the change feed of the ranges
+			return m.merge(data.Ranges, ""), nil
+		}
+		m = m.merge(data.Ranges, next)
+		if next == etag {
+			return m, nil
+		}
+		etag = next
+	}
+}
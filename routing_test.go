@@ -0,0 +1,175 @@
+package documentdb
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRanges serves the change feed of the partition key ranges, the etag is
+// the number of changes read
+type fakeRanges struct {
+	*MockServer
+	mu       sync.Mutex
+	changes  []PartitionKeyRange
+	requests []string
+}
+
+func newFakeRanges(changes ...PartitionKeyRange) *fakeRanges {
+	f := &fakeRanges{changes: changes}
+	f.MockServer = HandlerFactory(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests = append(f.requests, r.Header.Get(HeaderIfNonMatch))
+		start, _ := strconv.Atoi(r.Header.Get(HeaderIfNonMatch))
+		if start >= len(f.changes) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		end := start + 2
+		if end > len(f.changes) {
+			end = len(f.changes)
+		}
+		w.Header().Set(HeaderEtag, strconv.Itoa(end))
+		serveRanges(w, f.changes[start:end])
+	})
+	return f
+}
+
+func (f *fakeRanges) add(changes ...PartitionKeyRange) {
+	f.mu.Lock()
+	f.changes = append(f.changes, changes...)
+	f.requests = nil
+	f.mu.Unlock()
+}
+
+func (f *fakeRanges) cache() *PartitionKeyRangeCache {
+	return NewPartitionKeyRangeCache(f.DB())
+}
+
+func pkrange(id, min, max string, parents ...string) PartitionKeyRange {
+	return PartitionKeyRange{PartitionKeyRangeID: id, MinInclusive: min, MaxInclusive: max, Parents: parents}
+}
+
+func rangeIDs(ranges []PartitionKeyRange) (ids []string) {
+	for _, r := range ranges {
+		ids = append(ids, r.PartitionKeyRangeID)
+	}
+	return
+}
+
+func TestPartitionKeyRangeCache(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeRanges(pkrange("1", "55", "FF"), pkrange("0", "", "55"))
+	defer f.Close()
+	ctx := context.Background()
+	cache := f.cache()
+
+	m, err := cache.RoutingMap(ctx, "dbs/db/colls/coll/")
+	assert.Nil(err)
+	assert.Equal([]string{"0", "1"}, rangeIDs(m.Ranges()))
+	assert.Equal([]string{"", "2"}, f.requests)
+	for epk, id := range map[string]string{"": "0", "3A": "0", "55": "1", "FE": "1"} {
+		r, ok := m.RangeByEPK(epk)
+		assert.True(ok)
+		assert.Equal(id, r.PartitionKeyRangeID, epk)
+	}
+	_, ok := m.RangeByEPK("FF")
+	assert.False(ok)
+	assert.Equal([]string{"0", "1"}, rangeIDs(m.Overlapping("3A", "6B")))
+	assert.Equal([]string{"1"}, rangeIDs(m.Overlapping("55", "FF")))
+
+	// The cached map is returned
+	f.add()
+	cached, err := cache.RoutingMap(ctx, "dbs/db/colls/coll/")
+	assert.Nil(err)
+	assert.True(m == cached)
+	assert.Empty(f.requests)
+
+	// Split, the changes are read from the last etag
+	f.add(pkrange("2", "", "2A", "0"), pkrange("3", "2A", "55", "0"))
+	m, err = cache.Refresh(ctx, "dbs/db/colls/coll/")
+	assert.Nil(err)
+	assert.Equal([]string{"2", "3", "1"}, rangeIDs(m.Ranges()))
+	assert.Equal([]string{"2", "4"}, f.requests)
+	r, _ := m.RangeByEPK("3A")
+	assert.Equal("3", r.PartitionKeyRangeID)
+	_, ok = m.Range("0")
+	assert.False(ok)
+
+	// Merge
+	f.add(pkrange("4", "", "55", "2", "3"))
+	m, err = cache.Refresh(ctx, "dbs/db/colls/coll/")
+	assert.Nil(err)
+	assert.Equal([]string{"4", "1"}, rangeIDs(m.Ranges()))
+
+	// The ranges are read again after the map is invalidated
+	cache.Invalidate("dbs/db/colls/coll/")
+	f.add()
+	m, err = cache.RoutingMap(ctx, "dbs/db/colls/coll/")
+	assert.Nil(err)
+	assert.Equal([]string{"4", "1"}, rangeIDs(m.Ranges()))
+	assert.Equal([]string{"", "2", "4", "5"}, f.requests)
+}
+
+func TestPartitionKeyRangeCacheIncomplete(t *testing.T) {
+	assert := assert.New(t)
+	f := newFakeRanges(pkrange("0", "", "55"))
+	defer f.Close()
+
+	_, err := f.cache().RoutingMap(context.Background(), "dbs/db/colls/coll/")
+	assert.Equal(ErrIncompleteRoutingMap, err)
+	// The incomplete changes are read again from the beginning
+	assert.Equal([]string{"", "1", "", "1"}, f.requests)
+}
+
+func TestPartitionKeyRangeCacheUnchangedEtag(t *testing.T) {
+	assert := assert.New(t)
+	var requests int
+	s := HandlerFactory(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// The gateway keeps answering with the same etag
+		w.Header().Set(HeaderEtag, "1")
+		serveRanges(w, []PartitionKeyRange{pkrange("0", "", "FF")})
+	})
+	defer s.Close()
+	cache := NewPartitionKeyRangeCache(s.DB())
+
+	m, err := cache.RoutingMap(context.Background(), "dbs/db/colls/coll/")
+	assert.Nil(err)
+	assert.Equal([]string{"0"}, rangeIDs(m.Ranges()))
+	assert.Equal(2, requests)
+	_, err = cache.Refresh(context.Background(), "dbs/db/colls/coll/")
+	assert.Nil(err)
+	assert.Equal(3, requests)
+}
+
+func TestPartitionKeyRangeCacheCollections(t *testing.T) {
+	assert := assert.New(t)
+	started, slow := make(chan struct{}), make(chan struct{})
+	s := HandlerFactory(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dbs/db/colls/slow/pkranges/" {
+			close(started)
+			<-slow
+		}
+		serveRanges(w, []PartitionKeyRange{pkrange("0", "", "FF")})
+	})
+	defer s.Close()
+	cache := NewPartitionKeyRangeCache(s.DB())
+
+	done := make(chan error)
+	go func() {
+		_, err := cache.RoutingMap(context.Background(), "dbs/db/colls/slow/")
+		done <- err
+	}()
+	<-started
+	// A slow collection doesn't block the other ones
+	_, err := cache.RoutingMap(context.Background(), "dbs/db/colls/fast/")
+	assert.Nil(err)
+	close(slow)
+	assert.Nil(<-done)
+}
@@ -0,0 +1,222 @@
+package documentdb
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SessionContainer keeps the session tokens returned by the service, per
+// collection and partition key range, and attaches the merged token to the
+// subsequent reads of the same collection (read-your-writes for Session
+// consistency).
+// Collections are keyed by their link prefix ("dbs/{db}/colls/{coll}"), so
+// name based links and self links of the same collection are tracked apart.
+type SessionContainer struct {
+	mu     sync.RWMutex
+	tokens map[string]map[string]*sessionToken
+}
+
+// NewSessionContainer creates an empty SessionContainer, the zero value is
+// ready to use too
+func NewSessionContainer() *SessionContainer {
+	return &SessionContainer{tokens: make(map[string]map[string]*sessionToken)}
+}
+
+// Get returns the merged session token of the collection that owns link,
+// formatted as "{pkrange}:{token},{pkrange}:{token}"
+func (s *SessionContainer) Get(link string) string {
+	coll := collectionLink(link)
+	if coll == "" {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return formatSessionTokens(s.tokens[coll])
+}
+
+// Set records the session token returned for link, merging it with the
+// tokens already known for the same partition key ranges
+func (s *SessionContainer) Set(link, token string) {
+	coll := collectionLink(link)
+	if coll == "" || token == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens == nil {
+		s.tokens = make(map[string]map[string]*sessionToken)
+	}
+	ranges, ok := s.tokens[coll]
+	if !ok {
+		ranges = make(map[string]*sessionToken)
+		s.tokens[coll] = ranges
+	}
+	for _, part := range strings.Split(token, ",") {
+		i := strings.IndexByte(part, ':')
+		if i == -1 {
+			continue
+		}
+		t, ok := parseSessionToken(part[i+1:])
+		if !ok {
+			continue
+		}
+		id := part[:i]
+		ranges[id] = ranges[id].merge(t)
+	}
+}
+
+// Clear removes the session tokens of the collection that owns link
+// (e.g: after the collection was deleted or recreated)
+func (s *SessionContainer) Clear(link string) {
+	s.mu.Lock()
+	delete(s.tokens, collectionLink(link))
+	s.mu.Unlock()
+}
+
+// Export returns the state of the container, keyed by collection link, to
+// be transferred to another process and loaded with Import
+func (s *SessionContainer) Export() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state := make(map[string]string, len(s.tokens))
+	for coll, ranges := range s.tokens {
+		state[coll] = formatSessionTokens(ranges)
+	}
+	return state
+}
+
+// Import merges a state returned by Export into the container, the tokens
+// are recorded by Set
+func (s *SessionContainer) Import(state map[string]string) {
+	for coll, token := range state {
+		s.Set(coll, token)
+	}
+}
+
+// collectionLink returns the "dbs/{db}/colls/{coll}" prefix of link, or an
+// empty string if link is not scoped to a collection
+func collectionLink(link string) string {
+	parts := strings.Split(strings.Trim(link, "/"), "/")
+	if len(parts) < 4 || parts[0] != "dbs" || parts[2] != "colls" {
+		return ""
+	}
+	return strings.Join(parts[:4], "/")
+}
+
+func formatSessionTokens(ranges map[string]*sessionToken) string {
+	if len(ranges) == 0 {
+		return ""
+	}
+	ids := make([]string, 0, len(ranges))
+	for id := range ranges {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id + ":" + ranges[id].String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// sessionToken is a parsed partition key range session token. Either a
+// simple LSN ("42"), or a vector token ("{version}#{globalLSN}#{region}={LSN}...")
+type sessionToken struct {
+	simple    bool
+	version   int64
+	globalLSN int64
+	regions   map[int64]int64
+}
+
+func parseSessionToken(s string) (*sessionToken, bool) {
+	parts := strings.Split(s, "#")
+	if len(parts) == 1 {
+		lsn, err := strconv.ParseInt(s, 10, 64)
+		return &sessionToken{simple: true, globalLSN: lsn}, err == nil
+	}
+	t := &sessionToken{regions: make(map[int64]int64)}
+	var err error
+	if t.version, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return nil, false
+	}
+	if t.globalLSN, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return nil, false
+	}
+	for _, p := range parts[2:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, false
+		}
+		region, err := strconv.ParseInt(kv[0], 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		lsn, err := strconv.ParseInt(kv[1], 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		t.regions[region] = lsn
+	}
+	return t, true
+}
+
+// merge returns the token that is at least as recent as t and o
+func (t *sessionToken) merge(o *sessionToken) *sessionToken {
+	if t == nil {
+		return o
+	}
+	if t.simple != o.simple || t.version != o.version {
+		// Tokens of different versions (e.g: after a failover) can't be
+		// merged, the most recent one wins
+		if o.version > t.version || (o.version == t.version && o.globalLSN > t.globalLSN) {
+			return o
+		}
+		return t
+	}
+	merged := &sessionToken{
+		simple:    t.simple,
+		version:   t.version,
+		globalLSN: maxInt64(t.globalLSN, o.globalLSN),
+	}
+	if !t.simple {
+		merged.regions = make(map[int64]int64, len(t.regions))
+		for region, lsn := range t.regions {
+			merged.regions[region] = lsn
+		}
+		for region, lsn := range o.regions {
+			merged.regions[region] = maxInt64(merged.regions[region], lsn)
+		}
+	}
+	return merged
+}
+
+func (t *sessionToken) String() string {
+	if t.simple {
+		return strconv.FormatInt(t.globalLSN, 10)
+	}
+	regions := make([]int64, 0, len(t.regions))
+	for region := range t.regions {
+		regions = append(regions, region)
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i] < regions[j] })
+	var b strings.Builder
+	b.WriteString(strconv.FormatInt(t.version, 10))
+	b.WriteByte('#')
+	b.WriteString(strconv.FormatInt(t.globalLSN, 10))
+	for _, region := range regions {
+		b.WriteByte('#')
+		b.WriteString(strconv.FormatInt(region, 10))
+		b.WriteByte('=')
+		b.WriteString(strconv.FormatInt(t.regions[region], 10))
+	}
+	return b.String()
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,52 @@
+package documentdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionContainer(t *testing.T) {
+	assert := assert.New(t)
+	s := NewSessionContainer()
+
+	s.Set("dbs/db/colls/coll/docs/1", "0:1#10#1=10")
+	s.Set("/dbs/db/colls/coll/docs/", "0:1#8#1=12#2=3,1:1#5")
+	assert.Equal("0:1#10#1=12#2=3,1:1#5", s.Get("dbs/db/colls/coll/docs/2"), "Should merge tokens per partition key range")
+
+	s.Set("dbs/db/colls/coll/", "1:2#1")
+	assert.Equal("0:1#10#1=12#2=3,1:2#1", s.Get("dbs/db/colls/coll"), "Newer version should win")
+
+	s.Set("dbs/db/colls/other/docs/1", "0:42")
+	s.Set("dbs/db/colls/other/docs/1", "0:40")
+	assert.Equal("0:42", s.Get("dbs/db/colls/other/docs/"), "Should handle simple tokens")
+
+	s.Set("dbs/db", "0:1#1")
+	assert.Equal("", s.Get("dbs/db"), "Should ignore links without collection")
+
+	state := s.Export()
+	assert.Equal(map[string]string{
+		"dbs/db/colls/coll":  "0:1#10#1=12#2=3,1:2#1",
+		"dbs/db/colls/other": "0:42",
+	}, state)
+
+	imported := NewSessionContainer()
+	imported.Import(state)
+	assert.Equal(state, imported.Export())
+
+	s.Clear("dbs/db/colls/other/docs/1")
+	assert.Equal("", s.Get("dbs/db/colls/other"))
+}
+
+func TestSessionContainerZeroValue(t *testing.T) {
+	assert := assert.New(t)
+	var s SessionContainer
+	assert.Equal("", s.Get("dbs/db/colls/coll/docs/1"))
+	assert.Empty(s.Export())
+	s.Set("dbs/db/colls/coll/docs/1", "0:42")
+	assert.Equal("0:42", s.Get("dbs/db/colls/coll"))
+
+	var imported SessionContainer
+	imported.Import(map[string]string{"dbs/db/colls/coll": "0:42"})
+	assert.Equal("0:42", imported.Get("dbs/db/colls/coll"))
+}
@@ -0,0 +1,112 @@
+package documentdb
+
+import "context"
+
+// Read trigger by self link
+func (c *DocumentDB) ReadTrigger(link string, opts ...CallOption) (trigger *Trigger, err error) {
+	trigger, _, err = c.ReadTriggerWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadTriggerWithContext reads trigger by self link, the request is bound to ctx
+func (c *DocumentDB) ReadTriggerWithContext(ctx context.Context, link string, opts ...CallOption) (trigger *Trigger, res *Response, err error) {
+	if c.usesAAD() {
+		return nil, nil, errAAD
+	}
+
+	res, err = c.client.ReadWithContext(ctx, link, &trigger, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Read all triggers by collection self link
+func (c *DocumentDB) ReadTriggers(coll string, opts ...CallOption) (triggers []Trigger, err error) {
+	triggers, _, err = c.ReadTriggersWithContext(context.Background(), coll, opts...)
+	return
+}
+
+// ReadTriggersWithContext reads all triggers by collection self link, the request is bound to ctx
+func (c *DocumentDB) ReadTriggersWithContext(ctx context.Context, coll string, opts ...CallOption) (triggers []Trigger, res *Response, err error) {
+	return c.QueryTriggersWithContext(ctx, coll, nil, opts...)
+}
+
+// Read all collection `triggers` that satisfy a query
+func (c *DocumentDB) QueryTriggers(coll string, query *Query, opts ...CallOption) (triggers []Trigger, err error) {
+	triggers, _, err = c.QueryTriggersWithContext(context.Background(), coll, query, opts...)
+	return
+}
+
+// QueryTriggersWithContext reads all collection `triggers` that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryTriggersWithContext(ctx context.Context, coll string, query *Query, opts ...CallOption) (triggers []Trigger, res *Response, err error) {
+	if c.usesAAD() {
+		return nil, nil, errAAD
+	}
+
+	data := struct {
+		Triggers []Trigger `json:"Triggers,omitempty"`
+		Count    int       `json:"_count,omitempty"`
+	}{}
+	if query != nil {
+		res, err = c.client.QueryWithContext(ctx, coll+"triggers/", query, &data, opts...)
+	} else {
+		res, err = c.client.ReadWithContext(ctx, coll+"triggers/", &data, opts...)
+	}
+	if triggers = data.Triggers; err != nil {
+		triggers = nil
+	}
+	return
+}
+
+// Create trigger
+func (c *DocumentDB) CreateTrigger(coll string, body interface{}, opts ...CallOption) (trigger *Trigger, err error) {
+	trigger, _, err = c.CreateTriggerWithContext(context.Background(), coll, body, opts...)
+	return
+}
+
+// CreateTriggerWithContext creates trigger, the request is bound to ctx
+func (c *DocumentDB) CreateTriggerWithContext(ctx context.Context, coll string, body interface{}, opts ...CallOption) (trigger *Trigger, res *Response, err error) {
+	if c.usesAAD() {
+		return nil, nil, errAAD
+	}
+
+	res, err = c.client.CreateWithContext(ctx, coll+"triggers/", body, &trigger, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Replace trigger
+func (c *DocumentDB) ReplaceTrigger(link string, body interface{}, opts ...CallOption) (trigger *Trigger, err error) {
+	trigger, _, err = c.ReplaceTriggerWithContext(context.Background(), link, body, opts...)
+	return
+}
+
+// ReplaceTriggerWithContext replaces trigger, the request is bound to ctx
+func (c *DocumentDB) ReplaceTriggerWithContext(ctx context.Context, link string, body interface{}, opts ...CallOption) (trigger *Trigger, res *Response, err error) {
+	if c.usesAAD() {
+		return nil, nil, errAAD
+	}
+
+	res, err = c.client.ReplaceWithContext(ctx, link, body, &trigger, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Delete trigger
+func (c *DocumentDB) DeleteTrigger(link string, opts ...CallOption) (*Response, error) {
+	return c.DeleteTriggerWithContext(context.Background(), link, opts...)
+}
+
+// DeleteTriggerWithContext deletes trigger, the request is bound to ctx
+func (c *DocumentDB) DeleteTriggerWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	if c.usesAAD() {
+		return nil, errAAD
+	}
+
+	return c.client.DeleteWithContext(ctx, link, opts...)
+}
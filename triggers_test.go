@@ -0,0 +1,77 @@
+package documentdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReadTrigger(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "trigger_link", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadTrigger("trigger_link")
+	client.AssertCalled(t, "Read", "trigger_link", mock.Anything, mock.Anything)
+}
+
+func TestReadTriggers(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "coll_link/triggers/", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadTriggers("coll_link/")
+	client.AssertCalled(t, "Read", "coll_link/triggers/", mock.Anything, mock.Anything)
+}
+
+func TestQueryTriggers(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	q := NewQuery("SELECT * FROM ROOT r")
+	client.On("Query", "coll_link/triggers/", q).Return(nil)
+	c.QueryTriggers("coll_link/", q)
+	client.AssertCalled(t, "Query", "coll_link/triggers/", q)
+}
+
+func TestCreateTrigger(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	trigger := &Trigger{Body: "function() {}", TriggerType: TriggerPre, TriggerOperation: TriggerCreate}
+	client.On("Create", "coll_link/triggers/", trigger).Return(nil)
+	c.CreateTrigger("coll_link/", trigger)
+	client.AssertCalled(t, "Create", "coll_link/triggers/", trigger)
+}
+
+func TestReplaceTrigger(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Replace", "trigger_link", "{}").Return(nil)
+	c.ReplaceTrigger("trigger_link", "{}")
+	client.AssertCalled(t, "Replace", "trigger_link", "{}")
+}
+
+func TestDeleteTrigger(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Delete", "trigger_link").Return(nil)
+	c.DeleteTrigger("trigger_link")
+	client.AssertCalled(t, "Delete", "trigger_link")
+}
+
+type ServicePrincipalStub struct{}
+
+func (s *ServicePrincipalStub) EnsureFreshWithContext(ctx context.Context) error {
+	return nil
+}
+
+func (s *ServicePrincipalStub) OAuthToken() string {
+	return "token"
+}
+
+func TestTriggersWithAAD(t *testing.T) {
+	c := &DocumentDB{&ClientStub{}, &Config{ServicePrincipal: &ServicePrincipalStub{}}}
+	_, err := c.ReadTrigger("trigger_link")
+	assert.Equal(t, errAAD, err)
+	_, err = c.DeleteTrigger("trigger_link")
+	assert.Equal(t, errAAD, err)
+}
@@ -0,0 +1,181 @@
+package documentdb
+
+import "context"
+
+// Read user by self link
+func (c *DocumentDB) ReadUser(link string, opts ...CallOption) (user *User, err error) {
+	user, _, err = c.ReadUserWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadUserWithContext reads user by self link, the request is bound to ctx
+func (c *DocumentDB) ReadUserWithContext(ctx context.Context, link string, opts ...CallOption) (user *User, res *Response, err error) {
+	res, err = c.client.ReadWithContext(ctx, link, &user, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Read all users by db self link
+func (c *DocumentDB) ReadUsers(db string, opts ...CallOption) (users []User, err error) {
+	users, _, err = c.ReadUsersWithContext(context.Background(), db, opts...)
+	return
+}
+
+// ReadUsersWithContext reads all users by db self link, the request is bound to ctx
+func (c *DocumentDB) ReadUsersWithContext(ctx context.Context, db string, opts ...CallOption) (users []User, res *Response, err error) {
+	return c.QueryUsersWithContext(ctx, db, nil, opts...)
+}
+
+// Read all db users that satisfy a query
+func (c *DocumentDB) QueryUsers(db string, query *Query, opts ...CallOption) (users []User, err error) {
+	users, _, err = c.QueryUsersWithContext(context.Background(), db, query, opts...)
+	return
+}
+
+// QueryUsersWithContext reads all db users that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryUsersWithContext(ctx context.Context, db string, query *Query, opts ...CallOption) (users []User, res *Response, err error) {
+	data := struct {
+		Users []User `json:"Users,omitempty"`
+		Count int    `json:"_count,omitempty"`
+	}{}
+	if query != nil {
+		res, err = c.client.QueryWithContext(ctx, db+"users/", query, &data, opts...)
+	} else {
+		res, err = c.client.ReadWithContext(ctx, db+"users/", &data, opts...)
+	}
+	if users = data.Users; err != nil {
+		users = nil
+	}
+	return
+}
+
+// Create user
+func (c *DocumentDB) CreateUser(db string, body interface{}, opts ...CallOption) (user *User, err error) {
+	user, _, err = c.CreateUserWithContext(context.Background(), db, body, opts...)
+	return
+}
+
+// CreateUserWithContext creates user, the request is bound to ctx
+func (c *DocumentDB) CreateUserWithContext(ctx context.Context, db string, body interface{}, opts ...CallOption) (user *User, res *Response, err error) {
+	res, err = c.client.CreateWithContext(ctx, db+"users/", body, &user, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Replace user
+func (c *DocumentDB) ReplaceUser(link string, body interface{}, opts ...CallOption) (user *User, err error) {
+	user, _, err = c.ReplaceUserWithContext(context.Background(), link, body, opts...)
+	return
+}
+
+// ReplaceUserWithContext replaces user, the request is bound to ctx
+func (c *DocumentDB) ReplaceUserWithContext(ctx context.Context, link string, body interface{}, opts ...CallOption) (user *User, res *Response, err error) {
+	res, err = c.client.ReplaceWithContext(ctx, link, body, &user, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Delete user
+func (c *DocumentDB) DeleteUser(link string, opts ...CallOption) (*Response, error) {
+	return c.DeleteUserWithContext(context.Background(), link, opts...)
+}
+
+// DeleteUserWithContext deletes user, the request is bound to ctx
+func (c *DocumentDB) DeleteUserWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.client.DeleteWithContext(ctx, link, opts...)
+}
+
+// Read permission by self link
+func (c *DocumentDB) ReadPermission(link string, opts ...CallOption) (permission *Permission, err error) {
+	permission, _, err = c.ReadPermissionWithContext(context.Background(), link, opts...)
+	return
+}
+
+// ReadPermissionWithContext reads permission by self link, the request is bound to ctx
+func (c *DocumentDB) ReadPermissionWithContext(ctx context.Context, link string, opts ...CallOption) (permission *Permission, res *Response, err error) {
+	res, err = c.client.ReadWithContext(ctx, link, &permission, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Read all permissions by user self link
+func (c *DocumentDB) ReadPermissions(user string, opts ...CallOption) (permissions []Permission, err error) {
+	permissions, _, err = c.ReadPermissionsWithContext(context.Background(), user, opts...)
+	return
+}
+
+// ReadPermissionsWithContext reads all permissions by user self link, the request is bound to ctx
+func (c *DocumentDB) ReadPermissionsWithContext(ctx context.Context, user string, opts ...CallOption) (permissions []Permission, res *Response, err error) {
+	return c.QueryPermissionsWithContext(ctx, user, nil, opts...)
+}
+
+// Read all user permissions that satisfy a query
+func (c *DocumentDB) QueryPermissions(user string, query *Query, opts ...CallOption) (permissions []Permission, err error) {
+	permissions, _, err = c.QueryPermissionsWithContext(context.Background(), user, query, opts...)
+	return
+}
+
+// QueryPermissionsWithContext reads all user permissions that satisfy a query, the request is bound to ctx
+func (c *DocumentDB) QueryPermissionsWithContext(ctx context.Context, user string, query *Query, opts ...CallOption) (permissions []Permission, res *Response, err error) {
+	data := struct {
+		Permissions []Permission `json:"Permissions,omitempty"`
+		Count       int          `json:"_count,omitempty"`
+	}{}
+	if query != nil {
+		res, err = c.client.QueryWithContext(ctx, user+"permissions/", query, &data, opts...)
+	} else {
+		res, err = c.client.ReadWithContext(ctx, user+"permissions/", &data, opts...)
+	}
+	if permissions = data.Permissions; err != nil {
+		permissions = nil
+	}
+	return
+}
+
+// Create permission
+func (c *DocumentDB) CreatePermission(user string, body interface{}, opts ...CallOption) (permission *Permission, err error) {
+	permission, _, err = c.CreatePermissionWithContext(context.Background(), user, body, opts...)
+	return
+}
+
+// CreatePermissionWithContext creates permission, the request is bound to ctx
+func (c *DocumentDB) CreatePermissionWithContext(ctx context.Context, user string, body interface{}, opts ...CallOption) (permission *Permission, res *Response, err error) {
+	res, err = c.client.CreateWithContext(ctx, user+"permissions/", body, &permission, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Replace permission
+func (c *DocumentDB) ReplacePermission(link string, body interface{}, opts ...CallOption) (permission *Permission, err error) {
+	permission, _, err = c.ReplacePermissionWithContext(context.Background(), link, body, opts...)
+	return
+}
+
+// ReplacePermissionWithContext replaces permission, the request is bound to ctx
+func (c *DocumentDB) ReplacePermissionWithContext(ctx context.Context, link string, body interface{}, opts ...CallOption) (permission *Permission, res *Response, err error) {
+	res, err = c.client.ReplaceWithContext(ctx, link, body, &permission, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return
+}
+
+// Delete permission
+func (c *DocumentDB) DeletePermission(link string, opts ...CallOption) (*Response, error) {
+	return c.DeletePermissionWithContext(context.Background(), link, opts...)
+}
+
+// DeletePermissionWithContext deletes permission, the request is bound to ctx
+func (c *DocumentDB) DeletePermissionWithContext(ctx context.Context, link string, opts ...CallOption) (*Response, error) {
+	return c.client.DeleteWithContext(ctx, link, opts...)
+}
@@ -0,0 +1,98 @@
+package documentdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReadUser(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "user_link", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadUser("user_link")
+	client.AssertCalled(t, "Read", "user_link", mock.Anything, mock.Anything)
+}
+
+func TestReadUsers(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "db_link/users/", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadUsers("db_link/")
+	client.AssertCalled(t, "Read", "db_link/users/", mock.Anything, mock.Anything)
+}
+
+func TestQueryUsers(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	q := NewQuery("SELECT * FROM ROOT r")
+	client.On("Query", "db_link/users/", q).Return(nil)
+	c.QueryUsers("db_link/", q)
+	client.AssertCalled(t, "Query", "db_link/users/", q)
+}
+
+func TestCreateUser(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Create", "db_link/users/", `{"id":"user"}`).Return(nil)
+	c.CreateUser("db_link/", `{"id":"user"}`)
+	client.AssertCalled(t, "Create", "db_link/users/", `{"id":"user"}`)
+}
+
+func TestReplaceUser(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Replace", "user_link", "{}").Return(nil)
+	c.ReplaceUser("user_link", "{}")
+	client.AssertCalled(t, "Replace", "user_link", "{}")
+}
+
+func TestReadPermissions(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	client.On("Read", "user_link/permissions/", mock.Anything, mock.Anything).Return(nil, nil)
+	c.ReadPermissions("user_link/")
+	client.AssertCalled(t, "Read", "user_link/permissions/", mock.Anything, mock.Anything)
+}
+
+func TestCreatePermission(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+	permission := &Permission{PermissionMode: PermissionRead, ResourceLink: "dbs/db/colls/coll"}
+	client.On("Create", "user_link/permissions/", permission).Return(nil)
+	c.CreatePermission("user_link/", permission)
+	client.AssertCalled(t, "Create", "user_link/permissions/", permission)
+}
+
+func TestDeleteUsersAndPermissions(t *testing.T) {
+	client := &ClientStub{}
+	c := &DocumentDB{client, nil}
+
+	client.On("Delete", "user_link").Return(nil)
+	c.DeleteUser("user_link")
+	client.AssertCalled(t, "Delete", "user_link")
+
+	client.On("Delete", "permission_link").Return(nil)
+	c.DeletePermission("permission_link")
+	client.AssertCalled(t, "Delete", "permission_link")
+}
+
+func TestPermissionModel(t *testing.T) {
+	var permission Permission
+	err := Serialization.Unmarshal([]byte(`{
+		"id": "read-coll",
+		"permissionMode": "Read",
+		"resource": "dbs/db/colls/coll",
+		"resourcePartitionKey": ["tenant"],
+		"_token": "type=resource&ver=1&sig=abc"
+	}`), &permission)
+	assert.Nil(t, err)
+	assert.Equal(t, Permission{
+		Resource:             Resource{Id: "read-coll"},
+		PermissionMode:       PermissionRead,
+		ResourceLink:         "dbs/db/colls/coll",
+		ResourcePartitionKey: []interface{}{"tenant"},
+		Token:                "type=resource&ver=1&sig=abc",
+	}, permission)
+}